@@ -0,0 +1,47 @@
+package testchado
+
+import (
+	"archive/zip"
+	"bytes"
+	_ "embed"
+	"io/fs"
+)
+
+// The chado schema definitions, one file per backend named after its
+// database/sql driver(chado.postgres, chado.sqlite3)
+//
+//go:embed chado.zip
+var chadoArchive []byte
+
+// The preset fixtures(default.sql, cvprop.sql and eco.sql)
+//
+//go:embed preset.zip
+var presetArchive []byte
+
+// SchemaFS returns the chado schema definitions bundled with testchado as a read
+// only file system. It contains one file per backend named after the database/sql
+// driver, for example chado.postgres and chado.sqlite3.
+func SchemaFS() (fs.FS, error) {
+	return zipFS(chadoArchive)
+}
+
+// PresetFS returns the preset fixtures bundled with testchado as a read only file
+// system. Every fixture is a file named after the preset with a .sql extension.
+func PresetFS() (fs.FS, error) {
+	return zipFS(presetArchive)
+}
+
+func zipFS(archive []byte) (fs.FS, error) {
+	return zip.NewReader(bytes.NewReader(archive), int64(len(archive)))
+}
+
+// Reads the content of a file from the given file system
+func readFS(fsys fs.FS, name string) (*bytes.Buffer, error) {
+	var c bytes.Buffer
+	content, err := fs.ReadFile(fsys, name)
+	if err != nil {
+		return &c, err
+	}
+	c.Write(content)
+	return &c, nil
+}
@@ -0,0 +1,35 @@
+package testchado
+
+import (
+    "bytes"
+    "io/fs"
+    "testing"
+)
+
+func TestSchemaFS(t *testing.T) {
+    fsys, err := SchemaFS()
+    if err != nil {
+        t.Fatalf("should have opened the bundled schema: %s", err)
+    }
+    for _, name := range []string{"chado.postgres", "chado.sqlite3"} {
+        content, err := fs.ReadFile(fsys, name)
+        if err != nil {
+            t.Errorf("should have read %s: %s", name, err)
+        }
+        if !bytes.Contains(content, []byte("feature")) {
+            t.Errorf("%s should have contain feature", name)
+        }
+    }
+}
+
+func TestPresetFS(t *testing.T) {
+    fsys, err := PresetFS()
+    if err != nil {
+        t.Fatalf("should have opened the bundled presets: %s", err)
+    }
+    for _, name := range []string{"default.sql", "cvprop.sql", "eco.sql"} {
+        if _, err := fs.Stat(fsys, name); err != nil {
+            t.Errorf("should have bundled %s: %s", name, err)
+        }
+    }
+}
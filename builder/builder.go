@@ -0,0 +1,118 @@
+// Package builder provides fluent builders that create chado rows for a single
+// test, as an alternative to writing fixture files. The rows the built entity
+// refers to, like cv, cvterm, db, dbxref and organism, are looked up and created
+// if they are missing.
+//
+//	ids, err := builder.Feature("sadA").
+//	    Type("SO", "gene").
+//	    Organism("Dictyostelium", "discoideum").
+//	    Prop("description", "Sad gene").
+//	    Dbxref("DDB_G0288511").
+//	    Create(dbm)
+//
+// Every Create runs in a single transaction of the database handle of the
+// DBManager and returns the primary keys of the created rows.
+package builder
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/dictybase/testchado"
+	"github.com/dictybase/testchado/internal/chadodb"
+)
+
+// Name of the db of dbxrefs given without a DB: prefix
+const DefaultDb = "internal"
+
+// Runs fn in a transaction of the database handle of the manager
+func create(dbm testchado.DBManager, fn func(*chadodb.DB) error) error {
+	tx, err := dbm.DBHandle().Beginx()
+	if err != nil {
+		return err
+	}
+	if err := fn(chadodb.New(tx)); err != nil {
+		tx.Rollback()
+		return err
+	}
+	return tx.Commit()
+}
+
+// Returns the dbxref of a DB:accession identifier or of an accession in the
+// DefaultDb
+func dbxref(db *chadodb.DB, id string) (int64, error) {
+	if !strings.Contains(id, ":") {
+		id = DefaultDb + ":" + id
+	}
+	return db.EnsureDbxref(id)
+}
+
+// Returns the cvterm of an ontology:name reference through EnsureTerm, it is
+// created if needed. A DB:accession reference, one with a numeric accession like
+// GO:0005634, is never created and has to exist.
+func ontologyTerm(db *chadodb.DB, ref string) (int64, error) {
+	ontology, name, _ := strings.Cut(ref, ":")
+	if strings.Trim(name, "0123456789") != "" {
+		return db.EnsureTerm(ontology, name)
+	}
+	id, err := db.Resolve("cvterm", ref)
+	if err != nil {
+		return 0, fmt.Errorf("cvterm %s not found: %s", ref, err)
+	}
+	return id, nil
+}
+
+// Returns the abbreviation of an organism, the first letter of the genus and the
+// species, like D.discoideum
+func abbreviation(genus, species string) string {
+	r, _ := utf8.DecodeRuneInString(genus)
+	return string(r) + "." + species
+}
+
+// Returns the first letter of a word in upper case
+func initial(word string) string {
+	r, _ := utf8.DecodeRuneInString(word)
+	return string(unicode.ToUpper(r))
+}
+
+// Returns the organism of a genus and species, it is created if needed
+func organism(db *chadodb.DB, genus, species string) (int64, error) {
+	extra := map[string]interface{}{}
+	if len(genus) > 0 {
+		extra["abbreviation"] = abbreviation(genus, species)
+	}
+	return db.Ensure("organism", map[string]interface{}{"genus": genus, "species": species}, extra)
+}
+
+// A property of the built entity
+type prop struct {
+	name  string
+	value string
+}
+
+// Inserts the properties of a row in a prop table, typed by the cvterms of the
+// cv, the rank counts the values of the same type
+func insertProps(db *chadodb.DB, table, fk string, id int64, cv string, props []prop) ([]int64, error) {
+	var ids []int64
+	ranks := make(map[string]int)
+	for _, p := range props {
+		typeID, err := db.EnsureCvterm(cv, p.name)
+		if err != nil {
+			return nil, err
+		}
+		propID, err := db.Insert(table, map[string]interface{}{
+			fk:        id,
+			"type_id": typeID,
+			"value":   p.value,
+			"rank":    ranks[p.name],
+		})
+		if err != nil {
+			return nil, err
+		}
+		ranks[p.name]++
+		ids = append(ids, propID)
+	}
+	return ids, nil
+}
@@ -0,0 +1,94 @@
+package builder
+
+import (
+    "testing"
+
+    "github.com/dictybase/testchado"
+)
+
+type entries struct{ Counter int }
+
+func count(t *testing.T, dbm testchado.DBManager, query string, args ...interface{}) int {
+    e := entries{}
+    if err := dbm.DBHandle().Get(&e, dbm.DBHandle().Rebind(query), args...); err != nil {
+        t.Fatalf("should have executed the query %s", err)
+    }
+    return e.Counter
+}
+
+func TestFeature(t *testing.T) {
+    dbm := testchado.NewTestChado(t)
+    _ = dbm.LoadDefaultFixture()
+    ids, err := Feature("sadA").
+        Type("SO", "gene").
+        Organism("Dictyostelium", "discoideum").
+        Prop("description", "Sad gene").
+        Prop("note", "first").
+        Prop("note", "second").
+        Dbxref("DDB_G0288511").
+        Dbxref("UniProt:Q54IG6").
+        Residues("ATGC").
+        Create(dbm)
+    if err != nil {
+        t.Fatalf("should have created feature: %s", err)
+    }
+    if ids.Feature == 0 || len(ids.Props) != 3 || len(ids.Dbxrefs) != 2 {
+        t.Errorf("should have returned the generated ids, got %+v", ids)
+    }
+    q := `SELECT count(*) counter FROM feature
+        JOIN cvterm ON cvterm.cvterm_id = feature.type_id
+        JOIN cv ON cv.cv_id = cvterm.cv_id
+        JOIN organism ON organism.organism_id = feature.organism_id
+        WHERE feature.feature_id = ? AND cv.name = 'sequence' AND cvterm.name = 'gene'
+        AND organism.common_name = 'dicty' AND feature.dbxref_id = ? AND feature.seqlen = 4`
+    if n := count(t, dbm, q, ids.Feature, ids.Dbxrefs[0]); n != 1 {
+        t.Error("should have resolved the sequence ontology gene and the default organism")
+    }
+    if n := count(t, dbm, "SELECT count(*) counter FROM featureprop WHERE feature_id = ? AND rank = 1", ids.Feature); n != 1 {
+        t.Errorf("should have ranked the repeated property, got %d", n)
+    }
+    if n := count(t, dbm, "SELECT count(*) counter FROM feature_dbxref WHERE feature_id = ?", ids.Feature); n != 2 {
+        t.Errorf("should have 2 feature_dbxref, got %d", n)
+    }
+
+    other, err := Feature("sadB").Type("dicty_feature", "pseudogene").Organism("Dictyostelium", "purpureum").Create(dbm)
+    if err != nil {
+        t.Fatalf("should have created feature: %s", err)
+    }
+    if n := count(t, dbm, "SELECT count(*) counter FROM organism WHERE organism_id = ? AND abbreviation = 'D.purpureum'", other.Organism); n != 1 {
+        t.Error("should have created the organism")
+    }
+    if n := count(t, dbm, "SELECT count(*) counter FROM cvterm WHERE cvterm_id = ? AND name = 'pseudogene'", other.Type); n != 1 {
+        t.Error("should have created the type")
+    }
+    if _, err := Feature("sadC").Organism("Dictyostelium", "discoideum").Create(dbm); err == nil {
+        t.Error("should have failed without a type")
+    }
+}
+
+func TestOrganismAndCvterm(t *testing.T) {
+    dbm := testchado.NewTestChado(t)
+    id, err := Organism("Polysphondylium", "pallidum").CommonName("pallidum").Create(dbm)
+    if err != nil {
+        t.Fatalf("should have created organism: %s", err)
+    }
+    again, err := Organism("Polysphondylium", "pallidum").Create(dbm)
+    if err != nil || again != id {
+        t.Errorf("should have returned the existing organism %d, got %d %v", id, again, err)
+    }
+    if _, err := Organism("Ésquilo", "viridis").Create(dbm); err != nil {
+        t.Fatalf("should have created organism: %s", err)
+    }
+    if n := count(t, dbm, "SELECT count(*) counter FROM organism WHERE abbreviation = ?", "É.viridis"); n != 1 {
+        t.Error("should have abbreviated the genus by its first letter")
+    }
+    ids, err := Cvterm("dicty_stockcenter", "strain").Definition("A strain").Dbxref("DSC:strain").Create(dbm)
+    if err != nil {
+        t.Fatalf("should have created cvterm: %s", err)
+    }
+    q := `SELECT count(*) counter FROM cvterm JOIN dbxref ON dbxref.dbxref_id = cvterm.dbxref_id
+        WHERE cvterm_id = ? AND cv_id = ? AND dbxref.accession = 'strain'`
+    if n := count(t, dbm, q, ids.Cvterm, ids.Cv); n != 1 {
+        t.Error("should have created the cvterm with its dbxref")
+    }
+}
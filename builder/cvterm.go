@@ -0,0 +1,88 @@
+package builder
+
+import (
+	"github.com/dictybase/testchado"
+	"github.com/dictybase/testchado/internal/chadodb"
+)
+
+// Primary keys of a cvterm created by CvtermBuilder
+type CvtermIDs struct {
+	Cv     int64
+	Cvterm int64
+	Dbxref int64
+}
+
+// Builds a cvterm row
+type CvtermBuilder struct {
+	cv           string
+	name         string
+	definition   string
+	dbxref       string
+	relationship bool
+}
+
+// Starts a cvterm of a cv
+func Cvterm(cv, name string) *CvtermBuilder {
+	return &CvtermBuilder{cv: cv, name: name}
+}
+
+func (b *CvtermBuilder) Definition(definition string) *CvtermBuilder {
+	b.definition = definition
+	return b
+}
+
+// Sets the dbxref, either DB:accession or an accession of the DefaultDb,
+// otherwise the cvterm gets one in the internal db
+func (b *CvtermBuilder) Dbxref(id string) *CvtermBuilder {
+	b.dbxref = id
+	return b
+}
+
+// Marks the cvterm as a relationship type
+func (b *CvtermBuilder) Relationship() *CvtermBuilder {
+	b.relationship = true
+	return b
+}
+
+// Creates the cvterm, or returns the existing one of the cv, and returns the
+// primary keys of the cv, the cvterm and its dbxref
+func (b *CvtermBuilder) Create(dbm testchado.DBManager) (*CvtermIDs, error) {
+	ids := &CvtermIDs{}
+	err := create(dbm, func(db *chadodb.DB) error {
+		var err error
+		if ids.Cv, err = db.Ensure("cv", map[string]interface{}{"name": b.cv}, nil); err != nil {
+			return err
+		}
+		where := map[string]interface{}{"cv_id": ids.Cv, "name": b.name, "is_obsolete": 0}
+		ids.Cvterm, err = db.Lookup("cvterm", where)
+		if err != chadodb.ErrNotFound {
+			if err == nil {
+				err = db.QueryRowx(db.Rebind("SELECT dbxref_id FROM cvterm WHERE cvterm_id = ?"), ids.Cvterm).Scan(&ids.Dbxref)
+			}
+			return err
+		}
+		id := b.dbxref
+		if len(id) == 0 {
+			id = "internal:" + b.cv + ":" + b.name
+		}
+		if ids.Dbxref, err = dbxref(db, id); err != nil {
+			return err
+		}
+		row := map[string]interface{}{"dbxref_id": ids.Dbxref}
+		for k, v := range where {
+			row[k] = v
+		}
+		if len(b.definition) > 0 {
+			row["definition"] = b.definition
+		}
+		if b.relationship {
+			row["is_relationshiptype"] = 1
+		}
+		ids.Cvterm, err = db.Insert("cvterm", row)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return ids, nil
+}
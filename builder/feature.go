@@ -0,0 +1,184 @@
+package builder
+
+import (
+	"crypto/md5"
+	"encoding/hex"
+	"fmt"
+
+	"github.com/dictybase/testchado"
+	"github.com/dictybase/testchado/internal/chadodb"
+)
+
+// Primary keys of a feature created by FeatureBuilder
+type FeatureIDs struct {
+	Feature  int64
+	Type     int64
+	Organism int64
+	// the first one is also the dbxref_id of the feature
+	Dbxrefs        []int64
+	FeatureDbxrefs []int64
+	Props          []int64
+	Featureloc     int64
+}
+
+// Builds a feature row along with its properties and dbxrefs
+type FeatureBuilder struct {
+	name       string
+	uniquename string
+	ontology   string
+	typ        string
+	genus      string
+	species    string
+	residues   *string
+	dbxrefs    []string
+	props      []prop
+	location   *location
+}
+
+// Location of a feature on a source feature
+type location struct {
+	src        string
+	fmin, fmax int
+	strand     int
+}
+
+// Starts a feature, the name is also its uniquename unless UniqueName is given
+func Feature(name string) *FeatureBuilder {
+	return &FeatureBuilder{name: name, uniquename: name}
+}
+
+func (b *FeatureBuilder) UniqueName(uniquename string) *FeatureBuilder {
+	b.uniquename = uniquename
+	return b
+}
+
+// Type of the feature, the ontology is either the name of the cv or its db,
+// for example Type("sequence", "gene") or Type("SO", "gene")
+func (b *FeatureBuilder) Type(ontology, name string) *FeatureBuilder {
+	b.ontology = ontology
+	b.typ = name
+	return b
+}
+
+func (b *FeatureBuilder) Organism(genus, species string) *FeatureBuilder {
+	b.genus = genus
+	b.species = species
+	return b
+}
+
+// Sets the residues along with seqlen and md5checksum
+func (b *FeatureBuilder) Residues(residues string) *FeatureBuilder {
+	b.residues = &residues
+	return b
+}
+
+// Adds a featureprop typed by a cvterm of the feature_property cv
+func (b *FeatureBuilder) Prop(name, value string) *FeatureBuilder {
+	b.props = append(b.props, prop{name: name, value: value})
+	return b
+}
+
+// Locates the feature on a source feature of the same organism, given by its
+// uniquename. The coordinates are interbase as in featureloc.
+func (b *FeatureBuilder) Location(src string, fmin, fmax, strand int) *FeatureBuilder {
+	b.location = &location{src: src, fmin: fmin, fmax: fmax, strand: strand}
+	return b
+}
+
+// Adds a dbxref, either DB:accession or an accession of the DefaultDb. The
+// first one is the primary dbxref of the feature, all of them are linked
+// through feature_dbxref.
+func (b *FeatureBuilder) Dbxref(id string) *FeatureBuilder {
+	b.dbxrefs = append(b.dbxrefs, id)
+	return b
+}
+
+// Creates the feature and returns the primary keys of the created rows
+func (b *FeatureBuilder) Create(dbm testchado.DBManager) (*FeatureIDs, error) {
+	var ids *FeatureIDs
+	err := create(dbm, func(db *chadodb.DB) error {
+		var err error
+		ids, err = b.build(db)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return ids, nil
+}
+
+func (b *FeatureBuilder) build(db *chadodb.DB) (*FeatureIDs, error) {
+	if len(b.typ) == 0 {
+		return nil, fmt.Errorf("feature %s has no type", b.uniquename)
+	}
+	if len(b.genus) == 0 {
+		return nil, fmt.Errorf("feature %s has no organism", b.uniquename)
+	}
+	ids := &FeatureIDs{}
+	var err error
+	if ids.Type, err = db.EnsureTerm(b.ontology, b.typ); err != nil {
+		return nil, err
+	}
+	if ids.Organism, err = organism(db, b.genus, b.species); err != nil {
+		return nil, err
+	}
+	for _, d := range b.dbxrefs {
+		id, err := dbxref(db, d)
+		if err != nil {
+			return nil, err
+		}
+		ids.Dbxrefs = append(ids.Dbxrefs, id)
+	}
+	row := map[string]interface{}{
+		"name":        b.name,
+		"uniquename":  b.uniquename,
+		"type_id":     ids.Type,
+		"organism_id": ids.Organism,
+	}
+	if len(ids.Dbxrefs) > 0 {
+		row["dbxref_id"] = ids.Dbxrefs[0]
+	}
+	if b.residues != nil {
+		checksum := md5.Sum([]byte(*b.residues))
+		row["residues"] = *b.residues
+		row["seqlen"] = len(*b.residues)
+		row["md5checksum"] = hex.EncodeToString(checksum[:])
+	}
+	if ids.Feature, err = db.Insert("feature", row); err != nil {
+		return nil, err
+	}
+	for _, d := range ids.Dbxrefs {
+		id, err := db.Insert("feature_dbxref", map[string]interface{}{"feature_id": ids.Feature, "dbxref_id": d})
+		if err != nil {
+			return nil, err
+		}
+		ids.FeatureDbxrefs = append(ids.FeatureDbxrefs, id)
+	}
+	if ids.Props, err = insertProps(db, "featureprop", "feature_id", ids.Feature, "feature_property", b.props); err != nil {
+		return nil, err
+	}
+	if b.location != nil {
+		if ids.Featureloc, err = b.locate(db, ids); err != nil {
+			return nil, err
+		}
+	}
+	return ids, nil
+}
+
+func (b *FeatureBuilder) locate(db *chadodb.DB, ids *FeatureIDs) (int64, error) {
+	l := b.location
+	srcID, err := db.Lookup("feature", map[string]interface{}{"uniquename": l.src, "organism_id": ids.Organism})
+	if err == chadodb.ErrNotFound {
+		return 0, fmt.Errorf("source feature %s of %s not found", l.src, b.uniquename)
+	}
+	if err != nil {
+		return 0, err
+	}
+	return db.Insert("featureloc", map[string]interface{}{
+		"feature_id":    ids.Feature,
+		"srcfeature_id": srcID,
+		"fmin":          l.fmin,
+		"fmax":          l.fmax,
+		"strand":        l.strand,
+	})
+}
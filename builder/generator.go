@@ -0,0 +1,280 @@
+package builder
+
+import (
+	"fmt"
+	"math/rand"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/dictybase/testchado"
+	"github.com/dictybase/testchado/internal/chadodb"
+	"github.com/jmoiron/sqlx"
+)
+
+// Primary keys of a publication created by Generator
+type PubIDs struct {
+	Pub     int64
+	Authors []int64
+}
+
+// Generates random but plausible and referentially consistent chado rows. The
+// same seed always generates the same rows, so a failing property test could
+// be reproduced.
+//
+//	g := builder.NewGenerator(42)
+//	features, err := g.Features(dbm, 1000)
+type Generator struct {
+	seed int64
+	rnd  *rand.Rand
+	// identifiers handed out so far
+	used map[string]bool
+}
+
+// Returns a generator with its own random source
+func NewGenerator(seed int64) *Generator {
+	return &Generator{
+		seed: seed,
+		rnd:  rand.New(rand.NewSource(seed)),
+		used: make(map[string]bool),
+	}
+}
+
+// The seed the generator was created with
+func (g *Generator) Seed() int64 {
+	return g.seed
+}
+
+// Creates n organisms and returns their primary keys
+func (g *Generator) Organisms(dbm testchado.DBManager, n int) ([]int64, error) {
+	var ids []int64
+	err := create(dbm, func(db *chadodb.DB) error {
+		for i := 0; i < n; i++ {
+			o, err := g.organism(db)
+			if err != nil {
+				return err
+			}
+			ids = append(ids, o.ID)
+		}
+		return nil
+	})
+	return ids, err
+}
+
+// Creates n genes of a random organism along with one to three chromosomes,
+// every gene is located on one of them
+func (g *Generator) Features(dbm testchado.DBManager, n int) ([]*FeatureIDs, error) {
+	var ids []*FeatureIDs
+	err := create(dbm, func(db *chadodb.DB) error {
+		o, err := g.anyOrganism(db)
+		if err != nil {
+			return err
+		}
+		chromosomes := make([]string, 1+g.rnd.Intn(3))
+		lengths := make([]int, len(chromosomes))
+		for i := range chromosomes {
+			chromosomes[i] = g.identifier("chr", 6)
+			lengths[i] = 20000 + g.rnd.Intn(30000)
+			_, err := Feature(chromosomes[i]).
+				Type("SO", "chromosome").
+				Organism(o.Genus, o.Species).
+				Residues(g.residues(lengths[i])).
+				build(db)
+			if err != nil {
+				return err
+			}
+		}
+		for i := 0; i < n; i++ {
+			c := g.rnd.Intn(len(chromosomes))
+			length := 300 + g.rnd.Intn(4700)
+			fmin := g.rnd.Intn(lengths[c] - length)
+			strand := 1
+			if g.rnd.Intn(2) == 0 {
+				strand = -1
+			}
+			f, err := Feature(g.geneName()).
+				UniqueName(g.identifier("TC_G", 8)).
+				Type("SO", "gene").
+				Organism(o.Genus, o.Species).
+				Prop("description", g.sentence(4, 10)).
+				Location(chromosomes[c], fmin, fmin+length, strand).
+				build(db)
+			if err != nil {
+				return err
+			}
+			ids = append(ids, f)
+		}
+		return nil
+	})
+	return ids, err
+}
+
+// Creates n strains of a random organism, each with a genotype
+func (g *Generator) Stocks(dbm testchado.DBManager, n int) ([]*StockIDs, error) {
+	var ids []*StockIDs
+	err := create(dbm, func(db *chadodb.DB) error {
+		o, err := g.anyOrganism(db)
+		if err != nil {
+			return err
+		}
+		for i := 0; i < n; i++ {
+			alleles := make([]string, 1+g.rnd.Intn(3))
+			for j := range alleles {
+				alleles[j] = g.geneName() + "-"
+			}
+			s, err := Stock(g.identifier("TC_S", 7)).
+				Name(strings.ToUpper(g.word(1, 2))+strconv.Itoa(1+g.rnd.Intn(99))).
+				Description(g.sentence(3, 8)).
+				Type("stock_type", "strain").
+				Organism(o.Genus, o.Species).
+				Genotype(strings.Join(alleles, "/")).
+				build(db)
+			if err != nil {
+				return err
+			}
+			ids = append(ids, s)
+		}
+		return nil
+	})
+	return ids, err
+}
+
+// Creates n publications with a PMID and one to six authors
+func (g *Generator) Pubs(dbm testchado.DBManager, n int) ([]*PubIDs, error) {
+	var ids []*PubIDs
+	err := create(dbm, func(db *chadodb.DB) error {
+		typeID, err := db.EnsureCvterm("pub_type", "publication")
+		if err != nil {
+			return err
+		}
+		for i := 0; i < n; i++ {
+			p := &PubIDs{}
+			pmid := g.identifier("PMID:", 8)
+			p.Pub, err = db.Insert("pub", map[string]interface{}{
+				"uniquename":  pmid,
+				"title":       g.sentence(5, 12),
+				"series_name": g.title(g.word(2, 4)) + " " + g.title(g.word(2, 4)),
+				"volume":      strconv.Itoa(1 + g.rnd.Intn(300)),
+				"pyear":       strconv.Itoa(1980 + g.rnd.Intn(45)),
+				"pages":       g.pages(),
+				"type_id":     typeID,
+			})
+			if err != nil {
+				return err
+			}
+			dbxrefID, err := db.EnsureDbxref(pmid)
+			if err != nil {
+				return err
+			}
+			if _, err := db.Insert("pub_dbxref", map[string]interface{}{"pub_id": p.Pub, "dbxref_id": dbxrefID}); err != nil {
+				return err
+			}
+			authors := 1 + g.rnd.Intn(6)
+			for rank := 0; rank < authors; rank++ {
+				id, err := db.Insert("pubauthor", map[string]interface{}{
+					"pub_id":     p.Pub,
+					"rank":       rank,
+					"surname":    g.title(g.word(2, 4)),
+					"givennames": initial(g.word(1, 1)),
+				})
+				if err != nil {
+					return err
+				}
+				p.Authors = append(p.Authors, id)
+			}
+			ids = append(ids, p)
+		}
+		return nil
+	})
+	return ids, err
+}
+
+type generatedOrganism struct {
+	ID      int64 `db:"organism_id"`
+	Genus   string
+	Species string
+}
+
+func (g *Generator) organism(db *chadodb.DB) (*generatedOrganism, error) {
+	o := &generatedOrganism{Genus: g.title(g.word(3, 4)), Species: g.word(3, 5)}
+	var err error
+	o.ID, err = db.Insert("organism", map[string]interface{}{
+		"genus":        o.Genus,
+		"species":      o.Species,
+		"abbreviation": abbreviation(o.Genus, o.Species),
+		"common_name":  o.Species,
+	})
+	return o, err
+}
+
+// Returns a random organism of the database, one is created if there is none
+func (g *Generator) anyOrganism(db *chadodb.DB) (*generatedOrganism, error) {
+	var organisms []*generatedOrganism
+	err := sqlx.Select(db, &organisms, "SELECT organism_id, genus, species FROM organism ORDER BY organism_id")
+	if err != nil {
+		return nil, err
+	}
+	if len(organisms) == 0 {
+		return g.organism(db)
+	}
+	return organisms[g.rnd.Intn(len(organisms))], nil
+}
+
+// Returns a pronounceable word of the given number of syllables
+func (g *Generator) word(min, max int) string {
+	consonants := "bcdfghklmnprstvz"
+	vowels := "aeiou"
+	var b strings.Builder
+	for i := min + g.rnd.Intn(max-min+1); i > 0; i-- {
+		b.WriteByte(consonants[g.rnd.Intn(len(consonants))])
+		b.WriteByte(vowels[g.rnd.Intn(len(vowels))])
+	}
+	return b.String()
+}
+
+func (g *Generator) title(word string) string {
+	_, n := utf8.DecodeRuneInString(word)
+	return initial(word) + word[n:]
+}
+
+func (g *Generator) sentence(min, max int) string {
+	words := make([]string, min+g.rnd.Intn(max-min+1))
+	for i := range words {
+		words[i] = g.word(1, 4)
+	}
+	return g.title(strings.Join(words, " "))
+}
+
+// Returns a gene name in the dicty style, like sadA
+func (g *Generator) geneName() string {
+	return g.word(2, 2)[:3] + string(rune('A'+g.rnd.Intn(26)))
+}
+
+func (g *Generator) pages() string {
+	first := 1 + g.rnd.Intn(2000)
+	return fmt.Sprintf("%d-%d", first, first+1+g.rnd.Intn(30))
+}
+
+// Returns an identifier of the prefix followed by random digits, which was not
+// handed out before
+func (g *Generator) identifier(prefix string, digits int) string {
+	max := 1
+	for i := 0; i < digits; i++ {
+		max *= 10
+	}
+	for {
+		id := fmt.Sprintf("%s%0*d", prefix, digits, g.rnd.Intn(max))
+		if !g.used[id] {
+			g.used[id] = true
+			return id
+		}
+	}
+}
+
+func (g *Generator) residues(n int) string {
+	b := make([]byte, n)
+	for i := range b {
+		b[i] = "ACGT"[g.rnd.Intn(4)]
+	}
+	return string(b)
+}
@@ -0,0 +1,67 @@
+package builder
+
+import (
+    "testing"
+
+    "github.com/dictybase/testchado"
+)
+
+func TestGenerator(t *testing.T) {
+    dbm := testchado.NewTestChado(t)
+    g := NewGenerator(42)
+    if _, err := g.Organisms(dbm, 3); err != nil {
+        t.Fatalf("should have generated organisms: %s", err)
+    }
+    features, err := g.Features(dbm, 50)
+    if err != nil {
+        t.Fatalf("should have generated features: %s", err)
+    }
+    if len(features) != 50 {
+        t.Errorf("should have generated 50 features, got %d", len(features))
+    }
+    q := `SELECT count(*) counter FROM featureloc
+        JOIN feature src ON src.feature_id = featureloc.srcfeature_id
+        JOIN feature f ON f.feature_id = featureloc.feature_id
+        WHERE featureloc.fmax <= src.seqlen AND f.organism_id = src.organism_id`
+    if n := count(t, dbm, q); n != 50 {
+        t.Errorf("should have located 50 features within their chromosomes, got %d", n)
+    }
+    stocks, err := g.Stocks(dbm, 20)
+    if err != nil {
+        t.Fatalf("should have generated stocks: %s", err)
+    }
+    if n := count(t, dbm, "SELECT count(*) counter FROM stock_genotype"); n != len(stocks) {
+        t.Errorf("should have a genotype for every stock, got %d", n)
+    }
+    pubs, err := g.Pubs(dbm, 20)
+    if err != nil {
+        t.Fatalf("should have generated pubs: %s", err)
+    }
+    for _, p := range pubs {
+        if len(p.Authors) == 0 {
+            t.Errorf("should have authors for pub %d", p.Pub)
+        }
+    }
+
+    // the same seed generates the same rows
+    names := func(dbm testchado.DBManager) []string {
+        var names []string
+        if err := dbm.DBHandle().Select(&names, "SELECT uniquename FROM feature ORDER BY feature_id"); err != nil {
+            t.Fatal(err)
+        }
+        return names
+    }
+    other := testchado.NewTestChado(t)
+    og := NewGenerator(g.Seed())
+    _, _ = og.Organisms(other, 3)
+    _, _ = og.Features(other, 50)
+    expected, actual := names(dbm), names(other)
+    if len(expected) != len(actual) {
+        t.Fatalf("should have generated %d features, got %d", len(expected), len(actual))
+    }
+    for i := range expected {
+        if expected[i] != actual[i] {
+            t.Errorf("should have generated %s, got %s", expected[i], actual[i])
+        }
+    }
+}
@@ -0,0 +1,50 @@
+package builder
+
+import (
+	"github.com/dictybase/testchado"
+	"github.com/dictybase/testchado/internal/chadodb"
+)
+
+// Builds an organism row
+type OrganismBuilder struct {
+	genus        string
+	species      string
+	commonName   string
+	abbreviation string
+}
+
+// Starts an organism, the abbreviation defaults to the first letter of the
+// genus and the species, like D.discoideum
+func Organism(genus, species string) *OrganismBuilder {
+	b := &OrganismBuilder{genus: genus, species: species}
+	if len(genus) > 0 {
+		b.abbreviation = abbreviation(genus, species)
+	}
+	return b
+}
+
+func (b *OrganismBuilder) CommonName(name string) *OrganismBuilder {
+	b.commonName = name
+	return b
+}
+
+func (b *OrganismBuilder) Abbreviation(abbreviation string) *OrganismBuilder {
+	b.abbreviation = abbreviation
+	return b
+}
+
+// Creates the organism, or returns the existing one of the genus and species,
+// and returns its primary key
+func (b *OrganismBuilder) Create(dbm testchado.DBManager) (int64, error) {
+	var id int64
+	err := create(dbm, func(db *chadodb.DB) error {
+		extra := map[string]interface{}{"abbreviation": b.abbreviation}
+		if len(b.commonName) > 0 {
+			extra["common_name"] = b.commonName
+		}
+		var err error
+		id, err = db.Ensure("organism", map[string]interface{}{"genus": b.genus, "species": b.species}, extra)
+		return err
+	})
+	return id, err
+}
@@ -0,0 +1,292 @@
+package builder
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/dictybase/testchado"
+	"github.com/dictybase/testchado/internal/chadodb"
+	"github.com/jmoiron/sqlx"
+)
+
+// Primary keys of a phenotype created by PhenotypeBuilder
+type PhenotypeIDs struct {
+	Phenotype  int64
+	Observable int64
+	Attr       int64
+	Cvalue     int64
+	Assay      int64
+}
+
+// Builds a phenotype row, the cvterms it refers to are given either as cv:name,
+// DB:accession or by their name in any cv. Missing cvterms given by cv:name are
+// created in that cv and the ones given by name in a cv named after the column,
+// for example phenotype_observable. A DB:accession has to exist.
+type PhenotypeBuilder struct {
+	uniquename string
+	name       string
+	value      string
+	terms      map[string]string
+}
+
+// Starts a phenotype, the uniquename is also its name unless Name is given
+func Phenotype(uniquename string) *PhenotypeBuilder {
+	return &PhenotypeBuilder{uniquename: uniquename, name: uniquename, terms: make(map[string]string)}
+}
+
+func (b *PhenotypeBuilder) Name(name string) *PhenotypeBuilder {
+	b.name = name
+	return b
+}
+
+// The entity, for example a cell type or anatomy term, the phenotype is about
+func (b *PhenotypeBuilder) Observable(term string) *PhenotypeBuilder {
+	b.terms["observable"] = term
+	return b
+}
+
+// The attribute of the observable, for example a quality term
+func (b *PhenotypeBuilder) Attr(term string) *PhenotypeBuilder {
+	b.terms["attr"] = term
+	return b
+}
+
+// The value of the attribute as a cvterm
+func (b *PhenotypeBuilder) Cvalue(term string) *PhenotypeBuilder {
+	b.terms["cvalue"] = term
+	return b
+}
+
+// The assay the phenotype was observed with
+func (b *PhenotypeBuilder) Assay(term string) *PhenotypeBuilder {
+	b.terms["assay"] = term
+	return b
+}
+
+// The value of the attribute as free text
+func (b *PhenotypeBuilder) Value(value string) *PhenotypeBuilder {
+	b.value = value
+	return b
+}
+
+// Creates the phenotype and returns the primary keys of the created rows
+func (b *PhenotypeBuilder) Create(dbm testchado.DBManager) (*PhenotypeIDs, error) {
+	var ids *PhenotypeIDs
+	err := create(dbm, func(db *chadodb.DB) error {
+		var err error
+		ids, err = b.build(db)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return ids, nil
+}
+
+func (b *PhenotypeBuilder) build(db *chadodb.DB) (*PhenotypeIDs, error) {
+	ids := &PhenotypeIDs{}
+	row := map[string]interface{}{"uniquename": b.uniquename, "name": b.name}
+	if len(b.value) > 0 {
+		row["value"] = b.value
+	}
+	for _, t := range []struct {
+		column string
+		id     *int64
+	}{
+		{"observable", &ids.Observable},
+		{"attr", &ids.Attr},
+		{"cvalue", &ids.Cvalue},
+		{"assay", &ids.Assay},
+	} {
+		ref, ok := b.terms[t.column]
+		if !ok {
+			continue
+		}
+		var err error
+		if *t.id, err = term(db, ref, "phenotype_"+t.column); err != nil {
+			return nil, err
+		}
+		row[t.column+"_id"] = *t.id
+	}
+	var err error
+	if ids.Phenotype, err = db.Insert("phenotype", row); err != nil {
+		return nil, err
+	}
+	return ids, nil
+}
+
+// Returns the cvterm given by cv:name, DB:accession or by its name in any cv,
+// see ontologyTerm. A missing one given by its name is created in the given cv.
+func term(db *chadodb.DB, ref, cv string) (int64, error) {
+	if strings.Contains(ref, ":") {
+		return ontologyTerm(db, ref)
+	}
+	var ids []int64
+	err := sqlx.Select(db, &ids, db.Rebind("SELECT cvterm_id FROM cvterm WHERE name = ? AND is_obsolete = 0 ORDER BY cvterm_id"), ref)
+	if err != nil {
+		return 0, err
+	}
+	if len(ids) > 0 {
+		return ids[0], nil
+	}
+	return db.EnsureCvterm(cv, ref)
+}
+
+// Primary keys of a genotype created by GenotypeBuilder
+type GenotypeIDs struct {
+	Genotype int64
+	Type     int64
+}
+
+// Builds a genotype row
+type GenotypeBuilder struct {
+	uniquename  string
+	name        string
+	description string
+	ontology    string
+	typ         string
+}
+
+// Starts a genotype, the uniquename is also its name unless Name is given.
+// The type defaults to the genotype term of the sequence ontology.
+func Genotype(uniquename string) *GenotypeBuilder {
+	return &GenotypeBuilder{uniquename: uniquename, name: uniquename, ontology: "SO", typ: "genotype"}
+}
+
+func (b *GenotypeBuilder) Name(name string) *GenotypeBuilder {
+	b.name = name
+	return b
+}
+
+func (b *GenotypeBuilder) Description(description string) *GenotypeBuilder {
+	b.description = description
+	return b
+}
+
+// Type of the genotype, the ontology is either the name of the cv or its db
+func (b *GenotypeBuilder) Type(ontology, name string) *GenotypeBuilder {
+	b.ontology = ontology
+	b.typ = name
+	return b
+}
+
+// Creates the genotype and returns the primary keys of the created rows
+func (b *GenotypeBuilder) Create(dbm testchado.DBManager) (*GenotypeIDs, error) {
+	ids := &GenotypeIDs{}
+	err := create(dbm, func(db *chadodb.DB) error {
+		var err error
+		if ids.Type, err = db.EnsureTerm(b.ontology, b.typ); err != nil {
+			return err
+		}
+		row := map[string]interface{}{"uniquename": b.uniquename, "name": b.name, "type_id": ids.Type}
+		if len(b.description) > 0 {
+			row["description"] = b.description
+		}
+		ids.Genotype, err = db.Insert("genotype", row)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return ids, nil
+}
+
+// Primary keys of a phenstatement created by PhenstatementBuilder
+type PhenstatementIDs struct {
+	Phenstatement int64
+	Genotype      int64
+	Phenotype     int64
+	Environment   int64
+	Type          int64
+	Pub           int64
+}
+
+// Builds a phenstatement, the statement that a genotype shows a phenotype in an
+// environment according to a publication
+type PhenstatementBuilder struct {
+	genotype    string
+	phenotype   string
+	environment string
+	typ         string
+	pub         string
+}
+
+// Starts a phenstatement of a genotype and a phenotype given by their
+// uniquenames, both are created if needed
+func Phenstatement(genotype, phenotype string) *PhenstatementBuilder {
+	return &PhenstatementBuilder{genotype: genotype, phenotype: phenotype}
+}
+
+// Sets the environment by its uniquename, it is created if needed. Otherwise
+// the statement is made in the unspecified environment.
+func (b *PhenstatementBuilder) Environment(uniquename string) *PhenstatementBuilder {
+	b.environment = uniquename
+	return b
+}
+
+// Sets the type, given as cv:name, DB:accession or by its name in any cv,
+// otherwise it is observed of the phenstatement_type cv
+func (b *PhenstatementBuilder) Type(term string) *PhenstatementBuilder {
+	b.typ = term
+	return b
+}
+
+// Sets the publication by a DB:accession reference, for example PMID:4312,
+// otherwise the statement refers to the null publication
+func (b *PhenstatementBuilder) Pub(ref string) *PhenstatementBuilder {
+	b.pub = ref
+	return b
+}
+
+// Creates the phenstatement and returns the primary keys of the created rows
+func (b *PhenstatementBuilder) Create(dbm testchado.DBManager) (*PhenstatementIDs, error) {
+	ids := &PhenstatementIDs{}
+	err := create(dbm, func(db *chadodb.DB) error {
+		var err error
+		if ids.Genotype, err = genotype(db, b.genotype); err != nil {
+			return err
+		}
+		if ids.Phenotype, err = db.Ensure(
+			"phenotype",
+			map[string]interface{}{"uniquename": b.phenotype},
+			map[string]interface{}{"name": b.phenotype},
+		); err != nil {
+			return err
+		}
+		environment := b.environment
+		if len(environment) == 0 {
+			environment = "unspecified"
+		}
+		if ids.Environment, err = db.Ensure("environment", map[string]interface{}{"uniquename": environment}, nil); err != nil {
+			return err
+		}
+		if len(b.typ) > 0 {
+			ids.Type, err = term(db, b.typ, "phenstatement_type")
+		} else {
+			ids.Type, err = db.EnsureCvterm("phenstatement_type", "observed")
+		}
+		if err != nil {
+			return err
+		}
+		if len(b.pub) > 0 {
+			ids.Pub, err = db.EnsurePub(b.pub)
+		} else {
+			ids.Pub, err = db.NullPub()
+		}
+		if err != nil {
+			return fmt.Errorf("unable to resolve the pub of the phenstatement: %s", err)
+		}
+		ids.Phenstatement, err = db.Insert("phenstatement", map[string]interface{}{
+			"genotype_id":    ids.Genotype,
+			"phenotype_id":   ids.Phenotype,
+			"environment_id": ids.Environment,
+			"type_id":        ids.Type,
+			"pub_id":         ids.Pub,
+		})
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return ids, nil
+}
@@ -0,0 +1,107 @@
+package builder
+
+import (
+    "testing"
+
+    "github.com/dictybase/testchado"
+)
+
+func TestPhenotype(t *testing.T) {
+    dbm := testchado.NewTestChado(t)
+    _ = dbm.LoadDefaultFixture()
+    if _, err := Cvterm("Dicty Phenotypes", "aggregation").Create(dbm); err != nil {
+        t.Fatalf("should have created cvterm: %s", err)
+    }
+    ids, err := Phenotype("DDB_PHEN_1").
+        Name("delayed aggregation").
+        Observable("aggregation").
+        Attr("PATO:delayed").
+        Value("about 4 hours").
+        Create(dbm)
+    if err != nil {
+        t.Fatalf("should have created phenotype: %s", err)
+    }
+    q := `SELECT count(*) counter FROM cvterm JOIN cv ON cv.cv_id = cvterm.cv_id
+        WHERE cvterm.cvterm_id = ? AND cv.name = ?`
+    if n := count(t, dbm, q, ids.Observable, "Dicty Phenotypes"); n != 1 {
+        t.Error("should have resolved the observable by its name")
+    }
+    if n := count(t, dbm, q, ids.Attr, "PATO"); n != 1 {
+        t.Error("should have created the attribute in its cv")
+    }
+    if ids.Cvalue != 0 || ids.Assay != 0 {
+        t.Errorf("should have left cvalue and assay unset, got %+v", ids)
+    }
+    other, err := Phenotype("DDB_PHEN_2").Observable("aggregation").Cvalue("normal").Create(dbm)
+    if err != nil {
+        t.Fatalf("should have created phenotype: %s", err)
+    }
+    if other.Observable != ids.Observable {
+        t.Error("should have reused the observable")
+    }
+    if n := count(t, dbm, q, other.Cvalue, "phenotype_cvalue"); n != 1 {
+        t.Error("should have created the missing value in the phenotype_cvalue cv")
+    }
+    byAccession, err := Phenotype("DDB_PHEN_3").Observable("SO:0000704").Create(dbm)
+    if err != nil {
+        t.Fatalf("should have created phenotype: %s", err)
+    }
+    if n := count(t, dbm, "SELECT count(*) counter FROM cvterm WHERE cvterm_id = ? AND name = 'gene'", byAccession.Observable); n != 1 {
+        t.Error("should have resolved the observable by its accession")
+    }
+    if _, err := Phenotype("DDB_PHEN_4").Observable("GO:0005634").Create(dbm); err == nil {
+        t.Error("should have failed with a missing accession")
+    }
+    if n := count(t, dbm, "SELECT count(*) counter FROM cvterm WHERE name = '0005634'"); n != 0 {
+        t.Error("should not have created a cvterm for the missing accession")
+    }
+}
+
+func TestGenotype(t *testing.T) {
+    dbm := testchado.NewTestChado(t)
+    _ = dbm.LoadDefaultFixture()
+    ids, err := Genotype("DSC_G0001").Name("axeA-/axeB-").Description("double knockout").Create(dbm)
+    if err != nil {
+        t.Fatalf("should have created genotype: %s", err)
+    }
+    q := "SELECT count(*) counter FROM genotype WHERE genotype_id = ? AND name = ? AND type_id = ?"
+    if n := count(t, dbm, q, ids.Genotype, "axeA-/axeB-", ids.Type); n != 1 {
+        t.Error("should have stored the genotype")
+    }
+    if _, err := Genotype("DSC_G0001").Create(dbm); err == nil {
+        t.Error("should have failed with a duplicate uniquename")
+    }
+}
+
+func TestPhenstatement(t *testing.T) {
+    dbm := testchado.NewTestChado(t)
+    _ = dbm.LoadDefaultFixture()
+    if _, err := Genotype("DSC_G0001").Create(dbm); err != nil {
+        t.Fatalf("should have created genotype: %s", err)
+    }
+    ids, err := Phenstatement("DSC_G0001", "delayed aggregation").Create(dbm)
+    if err != nil {
+        t.Fatalf("should have created phenstatement: %s", err)
+    }
+    if ids.Environment == 0 || ids.Type == 0 || ids.Pub == 0 {
+        t.Errorf("should have filled in the defaults, got %+v", ids)
+    }
+    other, err := Phenstatement("DSC_G0001", "delayed aggregation").
+        Environment("starvation").
+        Type("inferred").
+        Pub("PMID:4312").
+        Create(dbm)
+    if err != nil {
+        t.Fatalf("should have created phenstatement: %s", err)
+    }
+    if other.Genotype != ids.Genotype || other.Phenotype != ids.Phenotype {
+        t.Errorf("should have reused the genotype and phenotype, got %+v and %+v", ids, other)
+    }
+    if other.Environment == ids.Environment || other.Pub == ids.Pub || other.Type == ids.Type {
+        t.Errorf("should have used the given environment, type and pub, got %+v", other)
+    }
+    q := "SELECT count(*) counter FROM phenstatement WHERE genotype_id = ?"
+    if n := count(t, dbm, q, ids.Genotype); n != 2 {
+        t.Errorf("should have stored 2 phenstatements, got %d", n)
+    }
+}
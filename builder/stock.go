@@ -0,0 +1,251 @@
+package builder
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/dictybase/testchado"
+	"github.com/dictybase/testchado/internal/chadodb"
+)
+
+// Primary keys of a stock created by StockBuilder or Generator
+type StockIDs struct {
+	Stock         int64
+	Organism      int64
+	Type          int64
+	Genotype      int64
+	StockGenotype int64
+	// the first one is also the dbxref_id of the stock
+	Dbxrefs       []int64
+	Props         []int64
+	Collections   []int64
+	Relationships []int64
+}
+
+// Builds a stock row along with its properties, dbxrefs, collections,
+// genotype and relationships to other stocks
+type StockBuilder struct {
+	uniquename    string
+	name          string
+	description   string
+	ontology      string
+	typ           string
+	genus         string
+	species       string
+	genotype      string
+	dbxrefs       []string
+	props         []prop
+	collections   []string
+	relationships []relationship
+}
+
+// A relationship to an object given by its uniquename
+type relationship struct {
+	typ    string
+	object string
+}
+
+// Starts a stock, the uniquename is also its name unless Name is given
+func Stock(uniquename string) *StockBuilder {
+	return &StockBuilder{uniquename: uniquename, name: uniquename}
+}
+
+func (b *StockBuilder) Name(name string) *StockBuilder {
+	b.name = name
+	return b
+}
+
+func (b *StockBuilder) Description(description string) *StockBuilder {
+	b.description = description
+	return b
+}
+
+// Type of the stock, the ontology is either the name of the cv or its db,
+// for example Type("dicty_stockcenter", "strain")
+func (b *StockBuilder) Type(ontology, name string) *StockBuilder {
+	b.ontology = ontology
+	b.typ = name
+	return b
+}
+
+func (b *StockBuilder) Organism(genus, species string) *StockBuilder {
+	b.genus = genus
+	b.species = species
+	return b
+}
+
+// Adds a stockprop typed by a cvterm of the stock_property cv
+func (b *StockBuilder) Prop(name, value string) *StockBuilder {
+	b.props = append(b.props, prop{name: name, value: value})
+	return b
+}
+
+// Adds a dbxref, either DB:accession or an accession of the DefaultDb. The
+// first one is the primary dbxref of the stock, all of them are linked
+// through stock_dbxref.
+func (b *StockBuilder) Dbxref(id string) *StockBuilder {
+	b.dbxrefs = append(b.dbxrefs, id)
+	return b
+}
+
+// Adds the stock to a stockcollection given by its uniquename, the collection
+// is created if needed
+func (b *StockBuilder) Collection(uniquename string) *StockBuilder {
+	b.collections = append(b.collections, uniquename)
+	return b
+}
+
+// Sets the genotype given by its uniquename, the genotype is created if
+// needed
+func (b *StockBuilder) Genotype(uniquename string) *StockBuilder {
+	b.genotype = uniquename
+	return b
+}
+
+// Adds a stock_relationship with the stock as subject. The object is a stock
+// given by its uniquename, the type is either cv:name, an existing DB:accession
+// or the name of a cvterm of the stock_relationship cv.
+func (b *StockBuilder) Relationship(typ, object string) *StockBuilder {
+	b.relationships = append(b.relationships, relationship{typ: typ, object: object})
+	return b
+}
+
+// Creates the stock and returns the primary keys of the created rows
+func (b *StockBuilder) Create(dbm testchado.DBManager) (*StockIDs, error) {
+	var ids *StockIDs
+	err := create(dbm, func(db *chadodb.DB) error {
+		var err error
+		ids, err = b.build(db)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return ids, nil
+}
+
+func (b *StockBuilder) build(db *chadodb.DB) (*StockIDs, error) {
+	if len(b.typ) == 0 {
+		return nil, fmt.Errorf("stock %s has no type", b.uniquename)
+	}
+	ids := &StockIDs{}
+	var err error
+	if ids.Type, err = db.EnsureTerm(b.ontology, b.typ); err != nil {
+		return nil, err
+	}
+	row := map[string]interface{}{
+		"uniquename": b.uniquename,
+		"name":       b.name,
+		"type_id":    ids.Type,
+	}
+	if len(b.description) > 0 {
+		row["description"] = b.description
+	}
+	if len(b.genus) > 0 {
+		if ids.Organism, err = organism(db, b.genus, b.species); err != nil {
+			return nil, err
+		}
+		row["organism_id"] = ids.Organism
+	}
+	for _, d := range b.dbxrefs {
+		id, err := dbxref(db, d)
+		if err != nil {
+			return nil, err
+		}
+		ids.Dbxrefs = append(ids.Dbxrefs, id)
+	}
+	if len(ids.Dbxrefs) > 0 {
+		row["dbxref_id"] = ids.Dbxrefs[0]
+	}
+	if ids.Stock, err = db.Insert("stock", row); err != nil {
+		return nil, err
+	}
+	for _, d := range ids.Dbxrefs {
+		if _, err := db.Insert("stock_dbxref", map[string]interface{}{"stock_id": ids.Stock, "dbxref_id": d}); err != nil {
+			return nil, err
+		}
+	}
+	if ids.Props, err = insertProps(db, "stockprop", "stock_id", ids.Stock, "stock_property", b.props); err != nil {
+		return nil, err
+	}
+	for _, c := range b.collections {
+		id, err := stockcollection(db, c)
+		if err != nil {
+			return nil, err
+		}
+		_, err = db.Insert("stockcollection_stock", map[string]interface{}{"stockcollection_id": id, "stock_id": ids.Stock})
+		if err != nil {
+			return nil, err
+		}
+		ids.Collections = append(ids.Collections, id)
+	}
+	if len(b.genotype) > 0 {
+		if ids.Genotype, err = genotype(db, b.genotype); err != nil {
+			return nil, err
+		}
+		ids.StockGenotype, err = db.Insert("stock_genotype", map[string]interface{}{"stock_id": ids.Stock, "genotype_id": ids.Genotype})
+		if err != nil {
+			return nil, err
+		}
+	}
+	for rank, r := range b.relationships {
+		id, err := b.relate(db, ids.Stock, r, rank)
+		if err != nil {
+			return nil, err
+		}
+		ids.Relationships = append(ids.Relationships, id)
+	}
+	return ids, nil
+}
+
+func (b *StockBuilder) relate(db *chadodb.DB, subjectID int64, r relationship, rank int) (int64, error) {
+	objectID, err := db.Lookup("stock", map[string]interface{}{"uniquename": r.object})
+	if err == chadodb.ErrNotFound {
+		return 0, fmt.Errorf("stock %s related to %s not found", r.object, b.uniquename)
+	}
+	if err != nil {
+		return 0, err
+	}
+	var typeID int64
+	if strings.Contains(r.typ, ":") {
+		typeID, err = ontologyTerm(db, r.typ)
+	} else {
+		typeID, err = db.EnsureTerm("stock_relationship", r.typ)
+	}
+	if err != nil {
+		return 0, err
+	}
+	return db.Insert("stock_relationship", map[string]interface{}{
+		"subject_id": subjectID,
+		"object_id":  objectID,
+		"type_id":    typeID,
+		"rank":       rank,
+	})
+}
+
+// Returns the stockcollection of a uniquename, it is created if needed
+func stockcollection(db *chadodb.DB, uniquename string) (int64, error) {
+	typeID, err := db.EnsureCvterm("stockcollection_type", "collection")
+	if err != nil {
+		return 0, err
+	}
+	return db.Ensure(
+		"stockcollection",
+		map[string]interface{}{"uniquename": uniquename},
+		map[string]interface{}{"name": uniquename, "type_id": typeID},
+	)
+}
+
+// Returns the genotype of a uniquename, it is created if needed with the
+// genotype term of the sequence ontology as type
+func genotype(db *chadodb.DB, uniquename string) (int64, error) {
+	typeID, err := db.EnsureTerm("SO", "genotype")
+	if err != nil {
+		return 0, err
+	}
+	return db.Ensure(
+		"genotype",
+		map[string]interface{}{"uniquename": uniquename},
+		map[string]interface{}{"name": uniquename, "type_id": typeID},
+	)
+}
@@ -0,0 +1,52 @@
+package builder
+
+import (
+    "testing"
+
+    "github.com/dictybase/testchado"
+)
+
+func TestStock(t *testing.T) {
+    dbm := testchado.NewTestChado(t)
+    _ = dbm.LoadDefaultFixture()
+    parent, err := Stock("DBS0235412").
+        Name("AX4").
+        Type("dicty_stockcenter", "strain").
+        Organism("Dictyostelium", "discoideum").
+        Collection("Dicty Stock Center").
+        Create(dbm)
+    if err != nil {
+        t.Fatalf("should have created stock: %s", err)
+    }
+    ids, err := Stock("DBS0351471").
+        Type("dicty_stockcenter", "strain").
+        Organism("Dictyostelium", "discoideum").
+        Prop("mutagenesis_method", "REMI").
+        Dbxref("DSC:DBS0351471").
+        Collection("Dicty Stock Center").
+        Genotype("axeA-/axeB-").
+        Relationship("derived_from", "DBS0235412").
+        Create(dbm)
+    if err != nil {
+        t.Fatalf("should have created stock: %s", err)
+    }
+    if ids.Type != parent.Type || ids.Organism != parent.Organism || ids.Collections[0] != parent.Collections[0] {
+        t.Errorf("should have reused the type, organism and collection, got %+v and %+v", parent, ids)
+    }
+    if ids.Genotype == 0 || len(ids.Props) != 1 || len(ids.Relationships) != 1 || len(ids.Dbxrefs) != 1 {
+        t.Errorf("should have returned the generated ids, got %+v", ids)
+    }
+    q := `SELECT count(*) counter FROM stock_relationship sr
+        JOIN cvterm ON cvterm.cvterm_id = sr.type_id
+        JOIN cv ON cv.cv_id = cvterm.cv_id
+        WHERE sr.subject_id = ? AND sr.object_id = ? AND cv.name = 'stock_relationship'`
+    if n := count(t, dbm, q, ids.Stock, parent.Stock); n != 1 {
+        t.Error("should have related the stock to its parent")
+    }
+    if _, err := Stock("DBS0000001").Type("dicty_stockcenter", "strain").Relationship("derived_from", "DBS9").Create(dbm); err == nil {
+        t.Error("should have failed with a missing related stock")
+    }
+    if _, err := Stock("DBS0000002").Type("dicty_stockcenter", "strain").Relationship("RO:0002203", "DBS0235412").Create(dbm); err == nil {
+        t.Error("should have failed with a missing relationship accession")
+    }
+}
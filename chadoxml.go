@@ -0,0 +1,550 @@
+package testchado
+
+import (
+	"bytes"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/dictybase/testchado/internal/chadodb"
+)
+
+// An element of a Chado-XML document
+type xmlElement struct {
+	name     string
+	attrs    map[string]string
+	children []*xmlElement
+	text     string
+	line     int
+}
+
+// Parses a Chado-XML document and returns its chado root element
+func parseChadoXML(r io.Reader) (*xmlElement, error) {
+	content, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	// line numbers are tracked from the offset of every token
+	var lastOffset int64
+	line := 1
+	lineAt := func(offset int64) int {
+		line += bytes.Count(content[lastOffset:offset], []byte("\n"))
+		lastOffset = offset
+		return line
+	}
+	dec := xml.NewDecoder(bytes.NewReader(content))
+	var root *xmlElement
+	var stack []*xmlElement
+	for {
+		offset := dec.InputOffset()
+		tok, err := dec.Token()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, &ParseError{Format: "chado-xml", Line: lineAt(dec.InputOffset()), Err: err}
+		}
+		switch t := tok.(type) {
+		case xml.StartElement:
+			el := &xmlElement{name: t.Name.Local, attrs: make(map[string]string), line: lineAt(offset)}
+			for _, a := range t.Attr {
+				el.attrs[a.Name.Local] = a.Value
+			}
+			switch {
+			case len(stack) > 0:
+				parent := stack[len(stack)-1]
+				parent.children = append(parent.children, el)
+			case root == nil:
+				root = el
+			}
+			stack = append(stack, el)
+		case xml.EndElement:
+			stack = stack[:len(stack)-1]
+		case xml.CharData:
+			if len(stack) > 0 {
+				stack[len(stack)-1].text += string(t)
+			}
+		}
+	}
+	if root == nil || root.name != "chado" {
+		return nil, &ParseError{Format: "chado-xml", Line: 1, Err: errors.New("chado root element is missing")}
+	}
+	return root, nil
+}
+
+// Loads the rows of a Chado-XML document
+type xmlLoader struct {
+	db *chadodb.DB
+	// macro id => primary key
+	macros map[string]int64
+	// unique keys of the tables loaded so far
+	keys map[string][][]string
+}
+
+// Loads a Chado-XML document in the chado schema. Every element is a row of the
+// table it is named after, its child elements are either columns or rows of
+// tables that refer to it, for example featureprop within feature. A foreign key
+// column could contain the row it refers to, the id of a row defined earlier in
+// the document(macro), a primary key or a natural key as in structured fixtures.
+//
+// A row is matched by the first unique key of its table the given columns
+// cover, otherwise by all the given columns. The op attribute of a row is one of
+//
+//	force    updates the other columns of the matching row or inserts it, the default
+//	lookup   uses the matching row, it has to exist
+//	insert   always inserts the row
+//	update   updates the other columns and the ones marked with op="update" of
+//	         the matching row
+//	delete   deletes the matching row
+//
+// A nested row of a table with subject_id and object_id columns, for example
+// feature_relationship, refers to the enclosing row through its subject_id. The
+// whole document is loaded in a single transaction, a failing element is
+// returned as *ParseError.
+func (dbh *DBHelper) LoadChadoXML(r io.Reader) error {
+	if !dbh.hasLoadedSchema {
+		return ErrSchemaNotLoaded
+	}
+	root, err := parseChadoXML(r)
+	if err != nil {
+		return err
+	}
+	tx, err := dbh.dbhandler.Beginx()
+	if err != nil {
+		return err
+	}
+	l := &xmlLoader{db: chadodb.New(tx), macros: make(map[string]int64), keys: make(map[string][][]string)}
+	for _, el := range root.children {
+		// _appdata and _sql elements are meant for other tools
+		if strings.HasPrefix(el.name, "_") {
+			continue
+		}
+		if _, err := l.row(el, "", 0); err != nil {
+			tx.Rollback()
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+func (l *xmlLoader) row(el *xmlElement, parent string, parentID int64) (int64, error) {
+	id, err := l.apply(el, parent, parentID)
+	if err != nil {
+		var perr *ParseError
+		if errors.As(err, &perr) {
+			return 0, err
+		}
+		return 0, &ParseError{Format: "chado-xml", Line: el.line, Err: err}
+	}
+	return id, nil
+}
+
+func (l *xmlLoader) apply(el *xmlElement, parent string, parentID int64) (int64, error) {
+	types, err := l.db.ColumnTypes(el.name)
+	if err != nil {
+		return 0, err
+	}
+	fks, err := l.db.ForeignKeys(el.name)
+	if err != nil {
+		return 0, err
+	}
+	where := make(map[string]interface{})
+	updates := make(map[string]interface{})
+	var nested []*xmlElement
+	for _, c := range el.children {
+		typ, ok := types[c.name]
+		if !ok {
+			if _, err := l.db.Columns(c.name); err != nil {
+				return 0, fmt.Errorf("table %s has no column %s", el.name, c.name)
+			}
+			nested = append(nested, c)
+			continue
+		}
+		var v interface{}
+		switch {
+		case len(c.children) > 0:
+			id, err := l.row(c.children[0], "", 0)
+			if err != nil {
+				return 0, err
+			}
+			v = id
+		case len(fks[c.name]) > 0:
+			id, err := l.reference(fks[c.name], strings.TrimSpace(c.text))
+			if err != nil {
+				return 0, err
+			}
+			v = id
+		default:
+			v = textValue(typ, strings.TrimSpace(c.text))
+		}
+		if c.attrs["op"] == "update" {
+			updates[c.name] = v
+			continue
+		}
+		where[c.name] = v
+	}
+	if len(parent) > 0 {
+		col := parentColumn(fks, parent, where)
+		if len(col) == 0 {
+			return 0, fmt.Errorf("table %s does not refer to %s", el.name, parent)
+		}
+		where[col] = parentID
+	}
+
+	op := el.attrs["op"]
+	if len(op) == 0 {
+		op = "force"
+	}
+	if op == "insert" {
+		id, err := l.db.Insert(el.name, merge(where, updates))
+		if err != nil {
+			return 0, err
+		}
+		return l.nested(el, id, nested)
+	}
+	key, rest, err := l.uniqueKey(el.name, where)
+	if err != nil {
+		return 0, err
+	}
+	var id int64
+	switch op {
+	case "force":
+		id, err = l.db.Lookup(el.name, key)
+		switch {
+		case err == chadodb.ErrNotFound:
+			id, err = l.db.Insert(el.name, merge(where, updates))
+		case err == nil && len(rest)+len(updates) > 0:
+			err = l.db.Update(el.name, id, merge(rest, updates))
+		}
+	case "lookup", "update", "delete":
+		id, err = l.db.Lookup(el.name, key)
+		if err == chadodb.ErrNotFound {
+			return 0, fmt.Errorf("no %s row matches the %s", el.name, op)
+		}
+		switch {
+		case err == nil && op == "update" && len(rest)+len(updates) > 0:
+			err = l.db.Update(el.name, id, merge(rest, updates))
+		case err == nil && op == "delete":
+			return id, l.db.Delete(el.name, id)
+		}
+	default:
+		return 0, fmt.Errorf("unknown op %s", op)
+	}
+	if err != nil {
+		return 0, err
+	}
+	return l.nested(el, id, nested)
+}
+
+// Records the macro id of a row and loads the rows nested in its element
+func (l *xmlLoader) nested(el *xmlElement, id int64, nested []*xmlElement) (int64, error) {
+	if macro := el.attrs["id"]; len(macro) > 0 {
+		l.macros[macro] = id
+	}
+	for _, n := range nested {
+		if _, err := l.row(n, el.name, id); err != nil {
+			return 0, err
+		}
+	}
+	return id, nil
+}
+
+// Splits the columns of a row into the ones of the first unique key of the
+// table they cover and the remaining ones. All columns are the key if they do
+// not cover any unique key.
+func (l *xmlLoader) uniqueKey(table string, row map[string]interface{}) (key, rest map[string]interface{}, err error) {
+	keys, ok := l.keys[table]
+	if !ok {
+		if keys, err = l.db.UniqueKeys(table); err != nil {
+			return nil, nil, err
+		}
+		l.keys[table] = keys
+	}
+	for _, cols := range keys {
+		key = make(map[string]interface{})
+		for _, c := range cols {
+			if v, ok := row[c]; ok {
+				key[c] = v
+			}
+		}
+		if len(key) < len(cols) {
+			continue
+		}
+		rest = make(map[string]interface{})
+		for c, v := range row {
+			if _, ok := key[c]; !ok {
+				rest[c] = v
+			}
+		}
+		return key, rest, nil
+	}
+	return row, nil, nil
+}
+
+// Resolves the content of a foreign key column
+func (l *xmlLoader) reference(table, ref string) (int64, error) {
+	if id, ok := l.macros[ref]; ok {
+		return id, nil
+	}
+	if id, err := strconv.ParseInt(ref, 10, 64); err == nil {
+		return id, nil
+	}
+	return l.db.Resolve(table, ref)
+}
+
+// Returns the foreign key column that refers to the enclosing row, subject_id is
+// preferred over the other columns
+func parentColumn(fks map[string]string, parent string, given map[string]interface{}) string {
+	if _, ok := given["subject_id"]; !ok && fks["subject_id"] == parent {
+		return "subject_id"
+	}
+	var cols []string
+	for c, t := range fks {
+		if _, ok := given[c]; !ok && t == parent {
+			cols = append(cols, c)
+		}
+	}
+	if len(cols) == 0 {
+		return ""
+	}
+	sort.Strings(cols)
+	return cols[0]
+}
+
+// Converts the text of a column to a boolean for boolean columns
+func textValue(typ, text string) interface{} {
+	if typ != "boolean" {
+		return text
+	}
+	switch strings.ToLower(text) {
+	case "1", "t", "true":
+		return true
+	case "0", "f", "false":
+		return false
+	}
+	return text
+}
+
+func merge(a, b map[string]interface{}) map[string]interface{} {
+	m := make(map[string]interface{})
+	for k, v := range a {
+		m[k] = v
+	}
+	for k, v := range b {
+		m[k] = v
+	}
+	return m
+}
+
+// Writes rows of the chado schema as Chado-XML
+type xmlExporter struct {
+	db  *chadodb.DB
+	enc *xml.Encoder
+	// macro ids that are already written
+	written map[string]bool
+}
+
+// Exports all rows of the given tables as Chado-XML. Rows of the other tables
+// that are referenced by the exported rows are written as lookups by their
+// unique columns, they have to exist wherever the document is loaded. Every row
+// gets an id, for example feature_12, which the rows after it refer to.
+func (dbh *DBHelper) ExportChadoXML(w io.Writer, tables ...string) error {
+	if !dbh.hasLoadedSchema {
+		return ErrSchemaNotLoaded
+	}
+	if len(tables) == 0 {
+		return errors.New("no table to export")
+	}
+	e := &xmlExporter{
+		db:      chadodb.New(dbh.dbhandler),
+		enc:     xml.NewEncoder(w),
+		written: make(map[string]bool),
+	}
+	refs := make(map[string][]string)
+	for _, t := range tables {
+		fks, err := e.db.ForeignKeys(t)
+		if err != nil {
+			return err
+		}
+		for _, ref := range fks {
+			refs[t] = append(refs[t], ref)
+		}
+	}
+	e.enc.Indent("", "  ")
+	root := xml.StartElement{Name: xml.Name{Local: "chado"}}
+	if err := e.enc.EncodeToken(root); err != nil {
+		return err
+	}
+	for _, t := range sortTables(tables, refs) {
+		if err := e.table(t); err != nil {
+			return err
+		}
+	}
+	if err := e.enc.EncodeToken(root.End()); err != nil {
+		return err
+	}
+	if err := e.enc.Flush(); err != nil {
+		return err
+	}
+	_, err := io.WriteString(w, "\n")
+	return err
+}
+
+func (e *xmlExporter) table(table string) error {
+	rows, err := e.db.Queryx(fmt.Sprintf("SELECT * FROM %s ORDER BY %s", table, chadodb.PrimaryKey(table)))
+	if err != nil {
+		return err
+	}
+	cols, err := rows.Columns()
+	if err != nil {
+		rows.Close()
+		return err
+	}
+	var all [][]interface{}
+	for rows.Next() {
+		values, err := rows.SliceScan()
+		if err != nil {
+			rows.Close()
+			return err
+		}
+		all = append(all, values)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+	for _, values := range all {
+		if err := e.row(table, cols, values, nil); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Writes a row, a lookup if only the key columns are given
+func (e *xmlExporter) row(table string, cols []string, values []interface{}, key map[string]bool) error {
+	fks, err := e.db.ForeignKeys(table)
+	if err != nil {
+		return err
+	}
+	pk := chadodb.PrimaryKey(table)
+	var id int64
+	children := make([][2]string, 0, len(cols))
+	for i, c := range cols {
+		v := values[i]
+		if c == pk {
+			id, _ = asInt64(v)
+			continue
+		}
+		if v == nil || (key != nil && !key[c]) {
+			continue
+		}
+		if ref, ok := fks[c]; ok {
+			refID, ok := asInt64(v)
+			if !ok {
+				return fmt.Errorf("unexpected %s.%s value %v", table, c, v)
+			}
+			// the referenced row has to come first
+			if err := e.lookup(ref, refID); err != nil {
+				return err
+			}
+			children = append(children, [2]string{c, xmlMacro(ref, refID)})
+			continue
+		}
+		children = append(children, [2]string{c, xmlText(v)})
+	}
+	start := xml.StartElement{
+		Name: xml.Name{Local: table},
+		Attr: []xml.Attr{{Name: xml.Name{Local: "id"}, Value: xmlMacro(table, id)}},
+	}
+	if key != nil {
+		start.Attr = append(start.Attr, xml.Attr{Name: xml.Name{Local: "op"}, Value: "lookup"})
+	}
+	if err := e.enc.EncodeToken(start); err != nil {
+		return err
+	}
+	for _, c := range children {
+		if err := e.enc.EncodeElement(c[1], xml.StartElement{Name: xml.Name{Local: c[0]}}); err != nil {
+			return err
+		}
+	}
+	e.written[xmlMacro(table, id)] = true
+	return e.enc.EncodeToken(start.End())
+}
+
+// Writes a lookup of a row that is not exported yet
+func (e *xmlExporter) lookup(table string, id int64) error {
+	if e.written[xmlMacro(table, id)] {
+		return nil
+	}
+	pk := chadodb.PrimaryKey(table)
+	row := make(map[string]interface{})
+	query := e.db.Rebind(fmt.Sprintf("SELECT * FROM %s WHERE %s = ?", table, pk))
+	if err := e.db.QueryRowx(query, id).MapScan(row); err != nil {
+		return fmt.Errorf("unable to export %s %d: %s", table, id, err)
+	}
+	keys, err := e.db.UniqueKeys(table)
+	if err != nil {
+		return err
+	}
+	key := make(map[string]bool)
+	if len(keys) > 0 {
+		for _, c := range keys[0] {
+			key[c] = true
+		}
+	} else {
+		for c := range row {
+			key[c] = c != pk
+		}
+	}
+	var cols []string
+	var values []interface{}
+	for _, c := range sortedColumns(row) {
+		cols = append(cols, c)
+		values = append(values, row[c])
+	}
+	return e.row(table, cols, values, key)
+}
+
+func sortedColumns(row map[string]interface{}) []string {
+	var cols []string
+	for c := range row {
+		cols = append(cols, c)
+	}
+	sort.Strings(cols)
+	return cols
+}
+
+func xmlMacro(table string, id int64) string {
+	return fmt.Sprintf("%s_%d", table, id)
+}
+
+func xmlText(v interface{}) string {
+	switch t := v.(type) {
+	case []byte:
+		return string(t)
+	case time.Time:
+		return t.Format("2006-01-02 15:04:05")
+	}
+	return fmt.Sprint(v)
+}
+
+func asInt64(v interface{}) (int64, bool) {
+	switch n := v.(type) {
+	case int64:
+		return n, true
+	case int32:
+		return int64(n), true
+	case int:
+		return int64(n), true
+	case []byte:
+		i, err := strconv.ParseInt(string(n), 10, 64)
+		return i, err == nil
+	}
+	return 0, false
+}
@@ -0,0 +1,133 @@
+package testchado
+
+import (
+    "bytes"
+    "errors"
+    "os"
+    "strings"
+    "testing"
+)
+
+func TestLoadChadoXML(t *testing.T) {
+    dbm := NewTestChado(t)
+    _ = dbm.LoadDefaultFixture()
+    fh, err := os.Open("testdata/sad.xml")
+    if err != nil {
+        t.Fatal(err)
+    }
+    defer fh.Close()
+    if err := dbm.LoadChadoXML(fh); err != nil {
+        t.Fatalf("should have loaded chado-xml file: %s", err)
+    }
+
+    type entries struct{ Counter int }
+    counts := map[string]string{
+        "feature":      "SELECT count(*) counter FROM feature",
+        "updated":      "SELECT count(*) counter FROM feature WHERE name = 'sadA1'",
+        "featureprop":  "SELECT count(*) counter FROM featureprop WHERE value = 'substrate adhesion'",
+        "relationship": "SELECT count(*) counter FROM feature_relationship fr JOIN feature f ON f.feature_id = fr.subject_id WHERE f.uniquename = 'DDB0191438'",
+    }
+    expected := map[string]int{
+        "feature":      2,
+        "updated":      1,
+        "featureprop":  1,
+        "relationship": 1,
+    }
+    for name, query := range counts {
+        e := entries{}
+        if err := dbm.DBHandle().Get(&e, query); err != nil {
+            t.Errorf("should have executed the %s query %s", name, err)
+        }
+        if e.Counter != expected[name] {
+            t.Errorf("should have %d %s rows, got %d", expected[name], name, e.Counter)
+        }
+    }
+}
+
+func TestLoadChadoXMLForce(t *testing.T) {
+    dbm := NewTestChado(t)
+    _ = dbm.LoadDefaultFixture()
+    content := `<chado>
+  <cv op="force">
+    <name>sequence</name>
+    <definition>Sequence ontology, reloaded</definition>
+  </cv>
+</chado>`
+    if err := dbm.LoadChadoXML(strings.NewReader(content)); err != nil {
+        t.Fatalf("should have force loaded the existing cv: %s", err)
+    }
+    type entries struct{ Counter int }
+    e := entries{}
+    if err := dbm.DBHandle().Get(&e, "SELECT count(*) counter FROM cv WHERE name = 'sequence' AND definition = 'Sequence ontology, reloaded'"); err != nil {
+        t.Errorf("should have executed the query %s", err)
+    }
+    if e.Counter != 1 {
+        t.Errorf("should have updated the definition of the existing cv, got %d", e.Counter)
+    }
+}
+
+func TestLoadChadoXMLError(t *testing.T) {
+    dbm := NewTestChado(t)
+    _ = dbm.LoadDefaultFixture()
+    content := `<chado>
+  <cv op="lookup">
+    <name>sequences</name>
+  </cv>
+</chado>`
+    var perr *ParseError
+    err := dbm.LoadChadoXML(strings.NewReader(content))
+    if !errors.As(err, &perr) || perr.Line != 2 {
+        t.Errorf("should have reported failed lookup at line 2: %s", err)
+    }
+    content = `<chado>
+  <cv>
+    <label>sequence</label>
+  </cv>
+</chado>`
+    err = dbm.LoadChadoXML(strings.NewReader(content))
+    if err == nil || !strings.Contains(err.Error(), "table cv has no column label") {
+        t.Errorf("should have reported unknown column: %s", err)
+    }
+    if err := dbm.LoadChadoXML(strings.NewReader("<chado><cv>")); !errors.As(err, &perr) {
+        t.Errorf("should have reported malformed xml: %s", err)
+    }
+}
+
+func TestExportChadoXML(t *testing.T) {
+    dbm := NewTestChado(t)
+    _ = dbm.LoadDefaultFixture()
+    fh, err := os.Open("testdata/sad.gff3")
+    if err != nil {
+        t.Fatal(err)
+    }
+    defer fh.Close()
+    if err := dbm.LoadGFF3(fh, "Dictyostelium discoideum"); err != nil {
+        t.Fatalf("should have loaded gff3 file: %s", err)
+    }
+    var b bytes.Buffer
+    if err := dbm.ExportChadoXML(&b, "featureloc", "feature", "feature_relationship"); err != nil {
+        t.Fatalf("should have exported chado-xml: %s", err)
+    }
+    if !strings.Contains(b.String(), `<cvterm id="cvterm_214" op="lookup">`) {
+        t.Error("should have written a lookup for the gene term")
+    }
+
+    other := NewTestChado(t)
+    _ = other.LoadDefaultFixture()
+    if err := other.LoadChadoXML(&b); err != nil {
+        t.Fatalf("should have loaded exported chado-xml: %s", err)
+    }
+    type entries struct{ Counter int }
+    for _, table := range []string{"feature", "featureloc", "feature_relationship"} {
+        e, o := entries{}, entries{}
+        if err := dbm.DBHandle().Get(&e, "SELECT count(*) counter FROM "+table); err != nil {
+            t.Errorf("should have executed the query %s", err)
+        }
+        if err := other.DBHandle().Get(&o, "SELECT count(*) counter FROM "+table); err != nil {
+            t.Errorf("should have executed the query %s", err)
+        }
+        if e.Counter != o.Counter {
+            t.Errorf("should have %d %s rows, got %d", e.Counter, table, o.Counter)
+        }
+    }
+}
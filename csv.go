@@ -0,0 +1,416 @@
+package testchado
+
+import (
+	"bufio"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/dictybase/testchado/internal/chadodb"
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+)
+
+// Number of bound parameters of a single INSERT statement, the lowest limit of
+// the sqlite builds around
+const csvMaxParams = 999
+
+// A column of a CSV file
+type csvColumn struct {
+	header string
+	column string
+	// referenced table of a foreign key column
+	ref string
+	// lookup columns of the referenced table, filled in from the parts of the
+	// value separated by seps
+	fields []string
+	seps   []string
+}
+
+// Loads the rows of a CSV file in a chado table. The header names the columns,
+// a foreign key column could also be given without the _id suffix and its values
+// are either primary keys or natural keys as in structured fixtures. A lookup
+// column spells out how the value identifies the referenced row as
+// column:fields, where the fields are columns of the referenced table joined by
+// any separator
+//
+//	type:cv.cvterm            sequence.gene, the cvterm gene of the cv sequence
+//	organism:genus species    Dictyostelium discoideum
+//	dbxref:db:accession       DDB:DDB_G0288511
+//
+// A field named after a foreign key of the referenced table, like cv or db, is
+// resolved by its name and one named after the table itself is its uniquename or
+// name. Empty values are NULL. The file is tab separated if the header contains
+// a tab. Rows are loaded with COPY on postgresql and with batched INSERTs on
+// sqlite and isolated managers in a single transaction, a failing row is returned as *ParseError.
+func (dbh *DBHelper) LoadCSV(table string, r io.Reader) error {
+	if !dbh.hasLoadedSchema {
+		return ErrSchemaNotLoaded
+	}
+	tx, err := dbh.dbhandler.Beginx()
+	if err != nil {
+		return err
+	}
+	if err := dbh.loadCSV(tx, table, r); err != nil {
+		tx.Rollback()
+		return err
+	}
+	return tx.Commit()
+}
+
+// Loads every .csv and .tsv file of a directory with LoadCSV in the table it is
+// named after, for example organism.csv or stock.tsv. The tables are loaded in
+// the order of their foreign keys within a single transaction.
+func (dbh *DBHelper) LoadCSVDir(dir string) error {
+	if !dbh.hasLoadedSchema {
+		return ErrSchemaNotLoaded
+	}
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return err
+	}
+	files := make(map[string]string)
+	var tables []string
+	for _, e := range entries {
+		ext := filepath.Ext(e.Name())
+		if e.IsDir() || (ext != ".csv" && ext != ".tsv") {
+			continue
+		}
+		table := strings.TrimSuffix(e.Name(), ext)
+		if _, ok := files[table]; ok {
+			return fmt.Errorf("more than one file for table %s in %s", table, dir)
+		}
+		files[table] = filepath.Join(dir, e.Name())
+		tables = append(tables, table)
+	}
+	tx, err := dbh.dbhandler.Beginx()
+	if err != nil {
+		return err
+	}
+	db := chadodb.New(tx)
+	refs := make(map[string][]string)
+	for _, t := range tables {
+		fks, err := db.ForeignKeys(t)
+		if err != nil {
+			tx.Rollback()
+			return err
+		}
+		for _, ref := range fks {
+			refs[t] = append(refs[t], ref)
+		}
+	}
+	for _, t := range sortTables(tables, refs) {
+		if err := dbh.loadCSVFile(tx, t, files[t]); err != nil {
+			tx.Rollback()
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+func (dbh *DBHelper) loadCSVFile(tx *sqlx.Tx, table, path string) error {
+	fh, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer fh.Close()
+	if err := dbh.loadCSV(tx, table, fh); err != nil {
+		return fmt.Errorf("%s: %w", path, err)
+	}
+	return nil
+}
+
+func (dbh *DBHelper) loadCSV(tx *sqlx.Tx, table string, r io.Reader) error {
+	br := bufio.NewReader(r)
+	first, err := br.Peek(4096)
+	if err != nil && err != io.EOF && err != bufio.ErrBufferFull {
+		return err
+	}
+	reader := csv.NewReader(br)
+	if line, _, _ := strings.Cut(string(first), "\n"); strings.Contains(line, "\t") {
+		reader.Comma = '\t'
+		reader.LazyQuotes = true
+	}
+	header, err := reader.Read()
+	if err == io.EOF {
+		return &ParseError{Format: "csv", Line: 1, Err: fmt.Errorf("header is missing")}
+	}
+	if err != nil {
+		return &ParseError{Format: "csv", Line: 1, Err: err}
+	}
+	db := chadodb.New(tx)
+	columns, err := csvHeader(db, table, header)
+	if err != nil {
+		return &ParseError{Format: "csv", Line: 1, Err: err}
+	}
+	types, err := db.ColumnTypes(table)
+	if err != nil {
+		return err
+	}
+	// all rows are resolved before loading, no other statement could run while
+	// COPY is in progress
+	cache := make(map[string]int64)
+	var rows [][]interface{}
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			line := 0
+			if pe, ok := err.(*csv.ParseError); ok {
+				line = pe.Line
+			}
+			return &ParseError{Format: "csv", Line: line, Err: err}
+		}
+		line, _ := reader.FieldPos(0)
+		row := make([]interface{}, len(columns))
+		for i, c := range columns {
+			if row[i], err = c.value(db, types[c.column], record[i], cache); err != nil {
+				return &ParseError{Format: "csv", Line: line, Err: err}
+			}
+		}
+		rows = append(rows, row)
+	}
+	if len(rows) == 0 {
+		return nil
+	}
+	names := make([]string, len(columns))
+	for i, c := range columns {
+		names[i] = c.column
+	}
+	// COPY needs a connection of its own, the shared transaction of an
+	// isolated manager falls back to INSERT statements
+	if dbh.driver == "postgres" && !dbh.isolated {
+		if err := copyRows(tx, table, names, rows); err != nil {
+			return err
+		}
+	} else if err := insertRows(tx, table, names, rows); err != nil {
+		return err
+	}
+	// the file might set the primary keys, so the sequences have to catch up
+	if dbh.driver == "postgres" {
+		_, err = tx.Exec(syncSequences)
+	}
+	return err
+}
+
+// Maps the header of a CSV file to the columns of a table
+func csvHeader(db *chadodb.DB, table string, header []string) ([]*csvColumn, error) {
+	cols, err := db.Columns(table)
+	if err != nil {
+		return nil, err
+	}
+	fks, err := db.ForeignKeys(table)
+	if err != nil {
+		return nil, err
+	}
+	seen := make(map[string]bool)
+	var columns []*csvColumn
+	for _, h := range header {
+		name, spec, lookup := strings.Cut(strings.TrimSpace(h), ":")
+		col := name
+		if !cols[col] && cols[col+"_id"] {
+			col = col + "_id"
+		}
+		if !cols[col] {
+			return nil, fmt.Errorf("table %s has no column %s", table, name)
+		}
+		if seen[col] {
+			return nil, fmt.Errorf("column %s is given more than once", col)
+		}
+		seen[col] = true
+		c := &csvColumn{header: h, column: col, ref: fks[col]}
+		if lookup {
+			if len(c.ref) == 0 {
+				return nil, fmt.Errorf("lookup column %s is not a foreign key", h)
+			}
+			if err := c.parseLookup(db, spec); err != nil {
+				return nil, err
+			}
+		}
+		columns = append(columns, c)
+	}
+	return columns, nil
+}
+
+// Splits the lookup spec in fields, words of letters, digits and underscores,
+// and the separators in between
+func (c *csvColumn) parseLookup(db *chadodb.DB, spec string) error {
+	isWord := func(r rune) bool {
+		return r == '_' || unicode.IsLetter(r) || unicode.IsDigit(r)
+	}
+	var words, seps []string
+	var token strings.Builder
+	inWord := true
+	for _, r := range spec {
+		if isWord(r) != inWord {
+			if inWord {
+				words = append(words, token.String())
+			} else {
+				seps = append(seps, token.String())
+			}
+			token.Reset()
+			inWord = !inWord
+		}
+		token.WriteRune(r)
+	}
+	words = append(words, token.String())
+	if !inWord || len(words[0]) == 0 || token.Len() == 0 {
+		return fmt.Errorf("lookup column %s has to start and end with a column of %s", c.header, c.ref)
+	}
+	cols, err := db.Columns(c.ref)
+	if err != nil {
+		return err
+	}
+	for _, w := range words {
+		switch {
+		case w == c.ref && cols["uniquename"]:
+			w = "uniquename"
+		case w == c.ref && cols["name"]:
+			w = "name"
+		case !cols[w] && cols[w+"_id"]:
+			w = w + "_id"
+		case !cols[w]:
+			return fmt.Errorf("lookup column %s: table %s has no column %s", c.header, c.ref, w)
+		}
+		c.fields = append(c.fields, w)
+	}
+	c.seps = seps
+	return nil
+}
+
+// Returns the value of a cell to load in the column
+func (c *csvColumn) value(db *chadodb.DB, typ, cell string, cache map[string]int64) (interface{}, error) {
+	if len(cell) == 0 {
+		return nil, nil
+	}
+	if len(c.ref) == 0 {
+		return textValue(typ, cell), nil
+	}
+	if id, err := strconv.ParseInt(cell, 10, 64); err == nil && len(c.fields) == 0 {
+		return id, nil
+	}
+	key := c.header + "\x00" + cell
+	if id, ok := cache[key]; ok {
+		return id, nil
+	}
+	var id int64
+	var err error
+	if len(c.fields) == 0 {
+		id, err = db.Resolve(c.ref, cell)
+	} else {
+		id, err = c.lookup(db, cell)
+	}
+	if err != nil {
+		return nil, err
+	}
+	cache[key] = id
+	return id, nil
+}
+
+func (c *csvColumn) lookup(db *chadodb.DB, cell string) (int64, error) {
+	fks, err := db.ForeignKeys(c.ref)
+	if err != nil {
+		return 0, err
+	}
+	where := make(map[string]interface{})
+	rest := cell
+	for i, f := range c.fields {
+		part := rest
+		if i < len(c.seps) {
+			var ok bool
+			part, rest, ok = strings.Cut(rest, c.seps[i])
+			if !ok {
+				return 0, fmt.Errorf("value %q does not match the lookup column %s", cell, c.header)
+			}
+		}
+		if ref, ok := fks[f]; ok {
+			id, err := db.Resolve(ref, part)
+			if err != nil {
+				return 0, err
+			}
+			where[f] = id
+			continue
+		}
+		where[f] = part
+	}
+	if _, ok := where["is_obsolete"]; !ok && c.ref == "cvterm" {
+		where["is_obsolete"] = 0
+	}
+	id, err := db.Lookup(c.ref, where)
+	if err == chadodb.ErrNotFound {
+		return 0, fmt.Errorf("unable to resolve %s %q of column %s", c.ref, cell, c.header)
+	}
+	return id, err
+}
+
+// Loads the rows through COPY FROM STDIN of postgresql
+func copyRows(tx *sqlx.Tx, table string, columns []string, rows [][]interface{}) error {
+	stmt, err := tx.Prepare(pq.CopyIn(table, columns...))
+	if err != nil {
+		return err
+	}
+	for _, row := range rows {
+		if _, err := stmt.Exec(row...); err != nil {
+			stmt.Close()
+			return fmt.Errorf("unable to copy in %s: %s", table, err)
+		}
+	}
+	if _, err := stmt.Exec(); err != nil {
+		stmt.Close()
+		return fmt.Errorf("unable to copy in %s: %s", table, err)
+	}
+	return stmt.Close()
+}
+
+// Loads the rows through prepared INSERT statements of many rows each
+func insertRows(tx *sqlx.Tx, table string, columns []string, rows [][]interface{}) error {
+	batch := csvMaxParams / len(columns)
+	if batch == 0 {
+		batch = 1
+	}
+	binds := "(" + strings.TrimSuffix(strings.Repeat("?, ", len(columns)), ", ") + ")"
+	prepare := func(n int) (*sqlx.Stmt, error) {
+		values := strings.TrimSuffix(strings.Repeat(binds+", ", n), ", ")
+		query := fmt.Sprintf("INSERT INTO %s (%s) VALUES %s", table, strings.Join(columns, ", "), values)
+		return tx.Preparex(tx.Rebind(query))
+	}
+	var stmt *sqlx.Stmt
+	size := 0
+	defer func() {
+		if stmt != nil {
+			stmt.Close()
+		}
+	}()
+	for start := 0; start < len(rows); start += batch {
+		end := start + batch
+		if end > len(rows) {
+			end = len(rows)
+		}
+		// only the last batch could be shorter
+		if end-start != size {
+			if stmt != nil {
+				stmt.Close()
+			}
+			var err error
+			if stmt, err = prepare(end - start); err != nil {
+				return err
+			}
+			size = end - start
+		}
+		var args []interface{}
+		for _, row := range rows[start:end] {
+			args = append(args, row...)
+		}
+		if _, err := stmt.Exec(args...); err != nil {
+			return fmt.Errorf("unable to insert in %s: %s", table, err)
+		}
+	}
+	return nil
+}
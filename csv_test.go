@@ -0,0 +1,72 @@
+package testchado
+
+import (
+    "errors"
+    "strings"
+    "testing"
+)
+
+func TestLoadCSVDir(t *testing.T) {
+    dbm := NewTestChado(t)
+    _ = dbm.LoadDefaultFixture()
+    if err := dbm.LoadCSVDir("testdata/csv"); err != nil {
+        t.Fatalf("should have loaded csv directory: %s", err)
+    }
+    type entries struct{ Counter int }
+    queries := map[string]int{
+        "SELECT count(*) counter FROM stock":                                        3,
+        "SELECT count(*) counter FROM cvterm WHERE is_obsolete = 0 AND name = 'strain'": 1,
+        `SELECT count(*) counter FROM stock JOIN cvterm ON cvterm.cvterm_id = stock.type_id
+          JOIN organism ON organism.organism_id = stock.organism_id
+          WHERE cvterm.name = 'strain' AND organism.species = 'purpureum'`: 1,
+        "SELECT count(*) counter FROM stock WHERE description = 'Axenic strain, \"commonly\" used'": 1,
+        "SELECT count(*) counter FROM stock WHERE description IS NULL":                             2,
+    }
+    for q, count := range queries {
+        e := entries{}
+        if err := dbm.DBHandle().Get(&e, q); err != nil {
+            t.Errorf("should have executed the query %s", err)
+        }
+        if e.Counter != count {
+            t.Errorf("should have %d rows for %s, got %d", count, q, e.Counter)
+        }
+    }
+}
+
+func TestLoadCSV(t *testing.T) {
+    dbm := NewTestChado(t)
+    _ = dbm.LoadDefaultFixture()
+    content := "uniquename,title,type:cv.cvterm\n" +
+        "PMID:1234,Dicty strains,sequence.gene\n" +
+        "PMID:5678,\"Axenic, growth\",sequence.gene\n"
+    if err := dbm.LoadCSV("pub", strings.NewReader(content)); err != nil {
+        t.Fatalf("should have loaded csv file: %s", err)
+    }
+    type entries struct{ Counter int }
+    e := entries{}
+    if err := dbm.DBHandle().Get(&e, "SELECT count(*) counter FROM pub WHERE title = 'Axenic, growth'"); err != nil {
+        t.Errorf("should have executed the query %s", err)
+    }
+    if e.Counter != 1 {
+        t.Errorf("should have 1 pub, got %d", e.Counter)
+    }
+
+    content = "uniquename,title,type:cv.cvterm\n" +
+        "PMID:9012,Unknown,sequence.nothing\n"
+    err := dbm.LoadCSV("pub", strings.NewReader(content))
+    var perr *ParseError
+    if !errors.As(err, &perr) {
+        t.Fatalf("should have returned a parse error, got %v", err)
+    }
+    if perr.Line != 2 {
+        t.Errorf("should have failed at line 2, got %d", perr.Line)
+    }
+    err = dbm.LoadCSV("pub", strings.NewReader("uniquename,colour\nPMID:1,red\n"))
+    if !errors.As(err, &perr) || perr.Line != 1 {
+        t.Errorf("should have failed at the header, got %v", err)
+    }
+    err = dbm.LoadCSV("organism", strings.NewReader("genus,species\nDicty\"ostelium,x\n"))
+    if !errors.As(err, &perr) || perr.Line != 2 {
+        t.Errorf("should have failed at the malformed line 2, got %v", err)
+    }
+}
@@ -1,21 +1,22 @@
 package testchado
 
 import (
-	"archive/zip"
 	"bytes"
 	"fmt"
 	"io"
-	"log"
+	"io/fs"
+	"math/rand"
 	"os"
 	"path/filepath"
-	"runtime"
+	"strings"
 
 	"github.com/jinzhu/gorm"
 	"github.com/jmoiron/sqlx"
 )
 
 // Interface for managing the lifecycle of a chado database. Any backend should implement
-// this interface
+// this interface. A sql statement that fails while deploying the schema or loading a
+// fixture is reported as *StatementError.
 type DBManager interface {
 	// Name of the database, might vary by implementation
 	Database() string
@@ -38,13 +39,74 @@ type DBManager interface {
 	//  2.Sequnence ontology(SO)
 	//  3.Relation ontology(RO)
 	LoadDefaultFixture() error
-	// Loads one of the preset fixture that comes bundled with testchado. Currently it could be one of
-	// cvprop or eco.
+	// Loads one of the preset fixture that comes bundled with testchado(cvprop or eco) or
+	// registered through RegisterPreset. An unknown preset is reported as *UnknownPresetError.
 	LoadPresetFixture(string) error
+	// List the name of preset fixtures that could be loaded by LoadPresetFixture
+	ListPresets() ([]string, error)
 	// Loads a custom fixture in the test database. It accepts file containing sql statements to insert fixture.
 	// The sql statements are generally series of INSERT statements one in a single line, however any other
 	// accpetable forms are allowed as long as they are compatible with the backend.
 	LoadCustomFixture(string) error
+	// Saves the current state of the chado schema, including the loaded fixtures, under
+	// the given name. Snapshots are discarded along with the schema by DropSchema.
+	Snapshot(string) error
+	// Brings back the chado schema to a state saved by Snapshot. An unknown snapshot is
+	// reported as ErrUnknownSnapshot.
+	Restore(string) error
+	// Writes the rows of the given tables, or all tables, as a fixture of INSERT
+	// statements that could be loaded back by LoadCustomFixture
+	DumpFixture(io.Writer, ...string) error
+}
+
+// Optional interface of the managers that load fixtures from other sources than
+// sql files. All the bundled backends implement it.
+type Loader interface {
+	// Loads a fixture file from any file system, for example an embed.FS bundled
+	// with the tests or os.DirFS. The file follows the same format as LoadCustomFixture.
+	LoadFixtureFS(fs.FS, string) error
+	// Loads the genome annotations of a GFF3 file for an organism, given by its
+	// genus and species or common name. It requires the sequence ontology from the
+	// default fixture.
+	LoadGFF3(io.Reader, string) error
+	// Loads an ontology from an OBO 1.2 or 1.4 file in the cv, cvterm, dbxref,
+	// cvterm_relationship and cvtermsynonym tables
+	LoadOntology(io.Reader) error
+	// Loads the sequences of a FASTA file as residues of features, missing features
+	// are created with the given sequence ontology type and organism
+	LoadFasta(io.Reader, string, string) error
+	// Loads the GO annotations of a GAF 2.x file in feature_cvterm, the evidence
+	// codes are resolved through the eco preset
+	LoadGAF(io.Reader) error
+	// Loads a Chado-XML document, rows could refer to existing ones through lookups
+	LoadChadoXML(io.Reader) error
+	// Loads the rows of a CSV or TSV file in a table, the header maps to the
+	// columns, which could be lookups like type:cv.cvterm
+	LoadCSV(string, io.Reader) error
+	// Loads every .csv and .tsv file of a directory in the table it is named after
+	LoadCSVDir(string) error
+}
+
+// Optional interface of the managers that export their rows as Chado-XML
+type Exporter interface {
+	// Exports all rows of the given tables as a Chado-XML document
+	ExportChadoXML(io.Writer, ...string) error
+}
+
+// Optional interface of the managers with a random source
+type Seeder interface {
+	// Seed of the random source of the manager, which also names the postgres
+	// schema, see TC_SEED
+	Seed() int64
+}
+
+// Chado is a DBManager along with all the optional interfaces, as implemented by
+// the bundled backends and returned by NewDBManager and NewTestChado
+type Chado interface {
+	DBManager
+	Loader
+	Exporter
+	Seeder
 }
 
 // A type that provides few helper attributes for implementing DBManager interface
@@ -55,40 +117,22 @@ type DBHelper struct {
 	dbhandler       *sqlx.DB
 	hasLoadedSchema bool
 	gormHandler     *gorm.DB
-}
-
-func currSrcDir() string {
-	_, filename, _, ok := runtime.Caller(1)
-	if !ok {
-		log.Fatal("unable to retreive current src file path")
-	}
-	return filepath.Dir(filename)
+	// presets loaded in the current schema
+	loadedPresets map[string]bool
+	// random source of the manager and its seed
+	seed int64
+	rnd  *rand.Rand
+	// statements run in the shared transaction of an isolated manager
+	isolated bool
 }
 
 // Return the content of chado schema for a particular backend
 func (dbh *DBHelper) SchemaDDL() (*bytes.Buffer, error) {
-	var c bytes.Buffer
-	zfile := filepath.Join(currSrcDir(), "chado.zip")
-	zr, err := zip.OpenReader(zfile)
+	fsys, err := SchemaFS()
 	if err != nil {
-		return &c, err
+		return &bytes.Buffer{}, err
 	}
-	defer zr.Close()
-	name := "chado." + dbh.Driver()
-	for _, f := range zr.File {
-		if f.Name == name {
-			zc, err := f.Open()
-			if err != nil {
-				return &c, err
-			}
-			_, err = io.Copy(&c, zc)
-			if err != nil {
-				return &c, err
-			}
-			break
-		}
-	}
-	return &c, nil
+	return readFS(fsys, "chado."+dbh.Driver())
 }
 
 // Loads the default fixture in the chado schema. The default fixture include.
@@ -96,74 +140,69 @@ func (dbh *DBHelper) SchemaDDL() (*bytes.Buffer, error) {
 //  2.Sequnence ontology(SO)
 //  3.Relation ontology(RO)
 func (dbh *DBHelper) LoadDefaultFixture() error {
+	return dbh.LoadPresetFixture("default")
+}
+
+// Loads a fixture file from the given file system, for example an embed.FS
+// or os.DirFS, in the test database
+func (dbh *DBHelper) LoadFixtureFS(fsys fs.FS, name string) error {
 	if !dbh.hasLoadedSchema {
-		return fmt.Errorf("chado schema is not loaded")
+		return ErrSchemaNotLoaded
 	}
-	var c bytes.Buffer
-	sqlx := dbh.dbhandler
-	zfile := filepath.Join(currSrcDir(), "preset.zip")
-	zr, err := zip.OpenReader(zfile)
+	c, err := readFS(fsys, name)
 	if err != nil {
 		return err
 	}
-	defer zr.Close()
-	for _, f := range zr.File {
-		if f.Name == "default.sql" {
-			zc, err := f.Open()
-			if err != nil {
-				return err
-			}
-			_, err = io.Copy(&c, zc)
-			if err != nil {
-				return err
-			}
-			break
-		}
-	}
-	_ = sqlx.MustExec(c.String())
-	return nil
+	return dbh.loadFixture(name, c.Bytes())
 }
 
-func (dbh *DBHelper) LoadPresetFixture(name string) error {
+func (dbh *DBHelper) LoadCustomFixture(file string) error {
 	if !dbh.hasLoadedSchema {
-		return fmt.Errorf("chado schema is not loaded")
+		return ErrSchemaNotLoaded
 	}
-	var c bytes.Buffer
-	sqlx := dbh.dbhandler
-	zfile := filepath.Join(currSrcDir(), "preset.zip")
-	zr, err := zip.OpenReader(zfile)
+
+	content, err := os.ReadFile(file)
 	if err != nil {
 		return err
 	}
-	defer zr.Close()
-	for _, f := range zr.File {
-		if f.Name == name+".sql" {
-			zc, err := f.Open()
-			if err != nil {
-				return err
-			}
-			_, err = io.Copy(&c, zc)
-			if err != nil {
-				return err
-			}
-			break
-		}
-	}
-	_ = sqlx.MustExec(c.String())
-	return nil
+	return dbh.loadFixture(file, content)
 }
 
-func (dbh *DBHelper) LoadCustomFixture(file string) error {
-	if !dbh.hasLoadedSchema {
-		return fmt.Errorf("chado schema is not loaded")
+// Loads a fixture within a single transaction. Files with .yaml, .yml and .json
+// extensions are structured fixtures, anything else contains sql statements. A
+// failing sql statement is returned as *StatementError, a failing structured row
+// as *RowError.
+func (dbh *DBHelper) loadFixture(source string, content []byte) error {
+	var f structuredFixture
+	var err error
+	switch strings.ToLower(filepath.Ext(source)) {
+	case ".yaml", ".yml":
+		f, err = parseYAMLFixture(content)
+	case ".json":
+		f, err = parseJSONFixture(content)
+	}
+	if err != nil {
+		return fmt.Errorf("unable to parse fixture %s: %s", source, err)
 	}
 
-	if _, err := os.Stat(file); os.IsNotExist(err) {
+	tx, err := dbh.dbhandler.Beginx()
+	if err != nil {
 		return err
 	}
-	//sqlx := dbh.dbhandler
-	_, err := sqlx.LoadFile(dbh.dbhandler, file)
-	return err
+	if f != nil {
+		err = loadStructured(tx, source, f)
+	} else {
+		err = execStatements(tx, source, string(content))
+	}
+	// fixtures might set the primary keys, so the sequences have to catch up
+	if err == nil && dbh.driver == "postgres" {
+		_, err = tx.Exec(syncSequences)
+	}
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+	return tx.Commit()
 }
 
 // The active database connection
@@ -176,6 +215,10 @@ func (db *DBHelper) GormHandle() *gorm.DB {
 	return db.gormHandler
 }
 
+func (dbh *DBHelper) helper() *DBHelper {
+	return dbh
+}
+
 func (dbh *DBHelper) Driver() string {
 	return dbh.driver
 }
@@ -185,16 +228,25 @@ func (dbh *DBHelper) DataSource() string {
 	return dbh.dbsource
 }
 
-// Returns a new instance of DBManager.
+// Returns a new instance of DBManager along with its optional interfaces.
 // By default, it gives an instance of sqlite backend.
 // If TC_DSOURCE env variable is set, returns a postgres backend.
-func NewDBManager() DBManager {
+func NewDBManager() Chado {
 	if CheckPostgresEnv() {
 		return NewPostgresManager(GetDataSource())
 	}
 	return NewSQLiteManager()
 }
 
+// Same as NewDBManager, however returns an error instead of exiting if the
+// backend could not be setup
+func NewDBManagerE() (Chado, error) {
+	if CheckPostgresEnv() {
+		return NewPostgresManagerE(GetDataSource())
+	}
+	return NewSQLiteManagerE()
+}
+
 func CheckPostgresEnv() bool {
 	if len(os.Getenv("TC_DSOURCE")) > 0 {
 		return true
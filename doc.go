@@ -38,15 +38,36 @@ Quick Start
         Expect(chado).Should(HaveDbxref("SO:0000704"))
     }
 
+Alternatively, NewTestChado deploys the schema and drops it once the test completes.
+
+    func TestQuickStart (t *testing.T) {
+        chado := testchado.NewTestChado(t)
+        chado.LoadDefaultFixture()
+        ...
+    }
+
 Run against a sqlite backend.
 
     go test
 
+By default the sqlite database lives in memory. To inspect the chado database after
+a failing test, use a file backed one. NewSQLiteTempManager keeps the file only if
+the test fails and logs its path.
+
+    chado := testchado.NewSQLiteTempManager(t)
+
 To run against an postgresql backend set the TC_DSOURCE variable.
 
     TC_DSOURCE="dbname=chado user=chado password=chado host=localhost sslmode=disable"
                                 \ go test
 
+The postgres schema names come from a random source of every manager. The seed of
+the run is logged when the first manager is created and again by a failing test of
+NewTestChado, set the TC_SEED variable to reproduce the run with the same schema
+names.
+
+    TC_SEED=1718022312 go test -run TestFeature
+
 
 
 Testing Arbitary SQL
@@ -96,6 +117,16 @@ number of rows from adhoc SQL statements with bind parameters.
         Expect(query).Should(HaveNameCount(m))
     }
 
+RegisterDBHandler is shared by all tests in a package. Parallel tests should bind
+the matchers to their own DBManager instead.
+
+    func TestParallel(t *testing.T) {
+        t.Parallel()
+        chado := testchado.NewTestChado(t)
+        m := For(chado)
+        NewGomegaWithT(t).Expect("SELECT count(*) FROM organism").Should(m.HaveCount(0))
+    }
+
 
 Loading Fixtures
 
@@ -109,6 +140,152 @@ handling fixtures ..
         most cases
         LoadPresetFixture("cvprop") // Either of cvprop or eco
         LoadCustomFixture("path") // A file containing SQL statements
+        LoadFixtureFS(fsys, "name.sql") // A file from any fs.FS, e.g. embed.FS
+
+Additional presets could be registered from any fs.FS, directory or zip archive
+and are loaded along with the presets they depend on.
+
+        testchado.RegisterPresetDir("dicty_strains", "testdata", "default")
+        chado.LoadPresetFixture("dicty_strains") // loads default first
+
+The chado schema and the preset fixtures are embedded in the package, so they
+are available regardless of where the source tree or the test binary lives.
+They could be accessed directly through SchemaFS and PresetFS.
+
+The loaders of other formats than sql below, ExportChadoXML and Seed are part of
+the optional interfaces Loader, Exporter and Seeder. The managers returned by
+NewDBManager and NewTestChado implement all of them as Chado, a DBManager from
+elsewhere has to be asserted first.
+
+        if loader, ok := dbm.(testchado.Loader); ok {
+            loader.LoadGFF3(fh, "Dictyostelium discoideum")
+        }
+
+Fixture files with .yaml, .yml or .json extension are structured fixtures, a list
+of tables with their rows. Foreign keys could refer to other rows by their natural
+keys instead of the primary keys, for example cv:name for cvterm, DB:accession for
+dbxref, "genus species" for organism and uniquename for feature.
+
+    - feature:
+        - uniquename: DDB_G0288511
+          organism: Dictyostelium discoideum
+          type: sequence:gene
+    - feature_relationship:
+        - subject: DDB0191438
+          object: DDB_G0288511
+          type: relationship:part_of
+
+Genome annotations could be loaded from GFF3 files on top of the default fixture,
+which provides the sequence ontology.
+
+        fh, _ := os.Open("testdata/chr1.gff3")
+        chado.LoadGFF3(fh, "Dictyostelium discoideum")
+
+Sequences are attached to the features from FASTA files, features that are not
+present yet are created with the given type.
+
+        fh, _ = os.Open("testdata/chr1.fasta")
+        chado.LoadFasta(fh, "chromosome", "Dictyostelium discoideum")
+
+GO annotations are loaded from GAF files once the annotated features and GO terms
+are present, the evidence codes come from the eco preset.
+
+        chado.LoadPresetFixture("eco")
+        ...
+        fh, _ = os.Open("testdata/dicty.gaf")
+        chado.LoadGAF(fh)
+
+Chado-XML documents, including the macro and lookup semantics, are loaded with
+LoadChadoXML. ExportChadoXML writes the rows of some tables back as Chado-XML, so
+the fixtures could be shared with other chado tools.
+
+        chado.ExportChadoXML(os.Stdout, "feature", "featureloc", "feature_relationship")
+
+A fixture built through the application code could be frozen with DumpFixture,
+which writes the rows as INSERT statements in dependency order.
+
+        fh, _ = os.Create("testdata/genes.sql")
+        chado.DumpFixture(fh, "feature", "featureloc", "featureprop")
+        ...
+        chado.LoadCustomFixture("testdata/genes.sql")
+
+Spreadsheets of strains, organisms or publications could be loaded as CSV or TSV
+files, one per table. Besides the columns of the table, the header could have
+lookup columns that tell how a value identifies the referenced row.
+
+        uniquename	name	type:cv.cvterm	organism:genus species
+        DBS0235412	AX4	dicty_stockcenter.strain	Dictyostelium discoideum
+
+        fh, _ = os.Open("testdata/strains.tsv")
+        chado.LoadCSV("stock", fh)
+        chado.LoadCSVDir("testdata/stockcenter") // stock.tsv, stockprop.csv ...
+
+Ontologies, for example a snippet of GO or SO, could be loaded from OBO files.
+Terms that are already present, for example the SO terms of the default fixture,
+are updated.
+
+        fh, _ := os.Open("testdata/go_slim.obo")
+        chado.LoadOntology(fh)
+
+Transaction Isolation
+
+Deploying the schema and loading fixtures for every test is slow, particularly
+for postgresql. Instead, deploy them once and isolate every test in a transaction
+that is rolled back once the test completes. Both DBHandle and GormHandle of the
+isolated manager run inside that transaction, transactions started by the code under
+test become savepoints.
+
+    var chado testchado.Chado
+
+    func TestMain(m *testing.M) {
+        chado = testchado.NewDBManager()
+        chado.DeploySchema()
+        chado.LoadDefaultFixture()
+        code := m.Run()
+        chado.DropSchema()
+        os.Exit(code)
+    }
+
+    func TestFeature(t *testing.T) {
+        dbm := testchado.Isolate(t, chado)
+        // use dbm.DBHandle() or dbm.GormHandle()
+    }
+
+Snapshots
+
+Loading large fixtures for every test is slow as well. Snapshot saves the
+current state of the chado schema, Restore brings it back within milliseconds.
+
+    chado.LoadPresetFixture("eco")
+    chado.Snapshot("eco")
+    ...
+    chado.Restore("eco") // before every test
+
+Builders
+
+Rows for a single test could be created in code through the builder package, the
+cvterms, dbxrefs and organisms they refer to are created as needed.
+
+    ids, err := builder.Feature("sadA").Type("SO", "gene").
+        Organism("Dictyostelium", "discoideum").Create(chado)
+    _, err = builder.Stock("DBS0351471").Type("dicty_stockcenter", "strain").
+        Collection("Dicty Stock Center").Genotype("axeA-/axeB-").
+        Relationship("derived_from", "DBS0235412").Create(chado)
+
+Phenotypes refer to their observable and attribute cvterms by name, cv:name or
+DB:accession, and a phenstatement ties a phenotype to a genotype.
+
+    _, err = builder.Phenotype("DDB_PHEN_1").Name("delayed aggregation").
+        Observable("aggregation").Attr("delayed").Create(chado)
+    _, err = builder.Phenstatement("axeA-/axeB-", "DDB_PHEN_1").
+        Environment("starvation").Pub("PMID:4312").Create(chado)
+
+For property tests, a Generator creates any number of random but referentially
+consistent organisms, located genes, stocks with genotypes and publications with
+authors. The same seed generates the same rows.
+
+    g := builder.NewGenerator(42)
+    features, err := g.Features(chado, 5000)
 
 Custom matchers
 
@@ -0,0 +1,105 @@
+package testchado
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/dictybase/testchado/internal/chadodb"
+)
+
+// Writes the rows of the given tables, or all tables if none is given, as INSERT
+// statements that could be loaded back by LoadCustomFixture on either backend.
+// The tables are ordered so that every table comes after the ones it refers to and
+// the rows keep their primary keys.
+func (dbh *DBHelper) DumpFixture(w io.Writer, tables ...string) error {
+	if !dbh.hasLoadedSchema {
+		return ErrSchemaNotLoaded
+	}
+	db := chadodb.New(dbh.dbhandler)
+	if len(tables) == 0 {
+		all, err := db.Tables()
+		if err != nil {
+			return err
+		}
+		tables = all
+	}
+	refs := make(map[string][]string)
+	for _, t := range tables {
+		fks, err := db.ForeignKeys(t)
+		if err != nil {
+			return err
+		}
+		for _, ref := range fks {
+			refs[t] = append(refs[t], ref)
+		}
+	}
+	bw := bufio.NewWriter(w)
+	for _, t := range sortTables(tables, refs) {
+		if err := dumpTable(db, bw, t); err != nil {
+			return err
+		}
+	}
+	return bw.Flush()
+}
+
+func dumpTable(db *chadodb.DB, w io.Writer, table string) error {
+	types, err := db.ColumnTypes(table)
+	if err != nil {
+		return err
+	}
+	rows, err := db.Queryx(fmt.Sprintf("SELECT * FROM %s ORDER BY %s", table, chadodb.PrimaryKey(table)))
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+	cols, err := rows.Columns()
+	if err != nil {
+		return err
+	}
+	prefix := fmt.Sprintf("INSERT INTO %s (%s) VALUES (", table, strings.Join(cols, ", "))
+	for rows.Next() {
+		values, err := rows.SliceScan()
+		if err != nil {
+			return err
+		}
+		literals := make([]string, len(values))
+		for i, v := range values {
+			literals[i] = sqlLiteral(types[cols[i]], v)
+		}
+		if _, err := fmt.Fprintf(w, "%s%s);\n", prefix, strings.Join(literals, ", ")); err != nil {
+			return err
+		}
+	}
+	return rows.Err()
+}
+
+// Returns the sql literal of a value that both backends understand
+func sqlLiteral(typ string, v interface{}) string {
+	switch t := v.(type) {
+	case nil:
+		return "NULL"
+	case bool:
+		return strings.ToUpper(strconv.FormatBool(t))
+	case int64:
+		// sqlite keeps booleans as integers
+		if typ == "boolean" {
+			return strings.ToUpper(strconv.FormatBool(t != 0))
+		}
+		return strconv.FormatInt(t, 10)
+	case float64:
+		return strconv.FormatFloat(t, 'g', -1, 64)
+	case time.Time:
+		return quoteLiteral(t.Format("2006-01-02 15:04:05.999999"))
+	case []byte:
+		return quoteLiteral(string(t))
+	}
+	return quoteLiteral(fmt.Sprint(v))
+}
+
+func quoteLiteral(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", "''") + "'"
+}
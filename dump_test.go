@@ -0,0 +1,93 @@
+package testchado
+
+import (
+    "os"
+    "path/filepath"
+    "strings"
+    "testing"
+)
+
+func TestDumpFixture(t *testing.T) {
+    dbm := NewTestChado(t)
+    _ = dbm.LoadDefaultFixture()
+    fh, err := os.Open("testdata/sad.gff3")
+    if err != nil {
+        t.Fatal(err)
+    }
+    defer fh.Close()
+    if err := dbm.LoadGFF3(fh, "Dictyostelium discoideum"); err != nil {
+        t.Fatalf("should have loaded gff3 file: %s", err)
+    }
+    _, err = dbm.DBHandle().Exec("UPDATE feature SET residues = 'it''s' WHERE uniquename = 'cds1'")
+    if err != nil {
+        t.Fatal(err)
+    }
+
+    fixture := filepath.Join(t.TempDir(), "sad.sql")
+    out, err := os.Create(fixture)
+    if err != nil {
+        t.Fatal(err)
+    }
+    defer out.Close()
+    if err := dbm.DumpFixture(out, "featureprop", "featureloc", "feature", "feature_relationship"); err != nil {
+        t.Fatalf("should have dumped fixture: %s", err)
+    }
+    content, _ := os.ReadFile(fixture)
+    if !strings.HasPrefix(string(content), "INSERT INTO feature (") {
+        t.Error("should have dumped feature table first")
+    }
+
+    other := NewTestChado(t)
+    _ = other.LoadDefaultFixture()
+    if err := other.LoadCustomFixture(fixture); err != nil {
+        t.Fatalf("should have loaded dumped fixture: %s", err)
+    }
+    type entries struct{ Counter int }
+    tables := []string{"feature", "featureloc", "featureprop", "feature_relationship"}
+    for _, table := range tables {
+        e, o := entries{}, entries{}
+        if err := dbm.DBHandle().Get(&e, "SELECT count(*) counter FROM "+table); err != nil {
+            t.Errorf("should have executed the query %s", err)
+        }
+        if err := other.DBHandle().Get(&o, "SELECT count(*) counter FROM "+table); err != nil {
+            t.Errorf("should have executed the query %s", err)
+        }
+        if e.Counter == 0 || e.Counter != o.Counter {
+            t.Errorf("should have %d %s rows, got %d", e.Counter, table, o.Counter)
+        }
+    }
+    e := entries{}
+    err = other.DBHandle().Get(&e, "SELECT count(*) counter FROM feature WHERE residues = 'it''s' AND is_obsolete = false")
+    if err != nil {
+        t.Errorf("should have executed the query %s", err)
+    }
+    if e.Counter != 1 {
+        t.Error("should have kept the quoted residues and boolean columns")
+    }
+}
+
+func TestDumpAllTables(t *testing.T) {
+    dbm := NewTestChado(t)
+    _ = dbm.LoadPresetFixture("cvprop")
+    fixture := filepath.Join(t.TempDir(), "all.sql")
+    out, err := os.Create(fixture)
+    if err != nil {
+        t.Fatal(err)
+    }
+    defer out.Close()
+    if err := dbm.DumpFixture(out); err != nil {
+        t.Fatalf("should have dumped fixture: %s", err)
+    }
+    other := NewTestChado(t)
+    if err := other.LoadCustomFixture(fixture); err != nil {
+        t.Fatalf("should have loaded dumped fixture: %s", err)
+    }
+    type entries struct{ Counter int }
+    e := entries{}
+    if err := other.DBHandle().Get(&e, "SELECT count(*) counter FROM cvterm"); err != nil {
+        t.Errorf("should have executed the query %s", err)
+    }
+    if e.Counter != 13 {
+        t.Errorf("should have 13 cvterms, got %d", e.Counter)
+    }
+}
@@ -0,0 +1,123 @@
+package testchado
+
+import (
+	"bufio"
+	"crypto/md5"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/dictybase/testchado/internal/chadodb"
+)
+
+// A sequence record of a FASTA file
+type fastaRecord struct {
+	line     int
+	id       string
+	residues string
+}
+
+// Parses the records of a FASTA file, the identifier is the first word of
+// the header line
+func parseFasta(r io.Reader) ([]*fastaRecord, error) {
+	var records []*fastaRecord
+	var current *fastaRecord
+	var residues strings.Builder
+	flush := func() {
+		if current != nil {
+			current.residues = residues.String()
+			records = append(records, current)
+		}
+		residues.Reset()
+	}
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 16*1024*1024)
+	line := 0
+	for scanner.Scan() {
+		line++
+		text := strings.TrimSpace(scanner.Text())
+		switch {
+		case len(text) == 0, strings.HasPrefix(text, ";"):
+		case strings.HasPrefix(text, ">"):
+			flush()
+			fields := strings.Fields(text[1:])
+			if len(fields) == 0 {
+				return nil, &ParseError{Format: "fasta", Line: line, Err: fmt.Errorf("header without identifier")}
+			}
+			current = &fastaRecord{line: line, id: fields[0]}
+		case current == nil:
+			return nil, &ParseError{Format: "fasta", Line: line, Err: fmt.Errorf("sequence without header")}
+		default:
+			for _, f := range strings.Fields(text) {
+				residues.WriteString(f)
+			}
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	flush()
+	return records, nil
+}
+
+// Loads the sequences of a FASTA file as the residues of features of an organism,
+// given by its genus and species or common name. The first word of the header is
+// the uniquename of the feature. Existing features get their residues, seqlen and
+// md5checksum updated, missing ones are created with the given sequence ontology
+// type, for example chromosome or SO:0000340. The whole file is loaded in a single
+// transaction, a failing record is returned as *ParseError.
+func (dbh *DBHelper) LoadFasta(r io.Reader, soType, organism string) error {
+	if !dbh.hasLoadedSchema {
+		return ErrSchemaNotLoaded
+	}
+	records, err := parseFasta(r)
+	if err != nil {
+		return err
+	}
+	tx, err := dbh.dbhandler.Beginx()
+	if err != nil {
+		return err
+	}
+	if err := loadFasta(chadodb.New(tx), records, soType, organism); err != nil {
+		tx.Rollback()
+		return err
+	}
+	return tx.Commit()
+}
+
+func loadFasta(db *chadodb.DB, records []*fastaRecord, soType, organism string) error {
+	organismID, err := db.Resolve("organism", organism)
+	if err != nil {
+		return err
+	}
+	var typeID int64
+	for _, rec := range records {
+		checksum := md5.Sum([]byte(rec.residues))
+		row := map[string]interface{}{
+			"residues":    rec.residues,
+			"seqlen":      len(rec.residues),
+			"md5checksum": hex.EncodeToString(checksum[:]),
+		}
+		id, err := db.Lookup("feature", map[string]interface{}{"organism_id": organismID, "uniquename": rec.id})
+		switch {
+		case err == nil:
+			err = db.Update("feature", id, row)
+		case err == chadodb.ErrNotFound:
+			// the type is only needed for new features
+			if typeID == 0 {
+				if typeID, err = db.SequenceTerm(soType); err != nil {
+					break
+				}
+			}
+			row["organism_id"] = organismID
+			row["uniquename"] = rec.id
+			row["type_id"] = typeID
+			_, err = db.Insert("feature", row)
+		}
+		if err != nil {
+			return &ParseError{Format: "fasta", Line: rec.line, Err: err}
+		}
+	}
+	return nil
+}
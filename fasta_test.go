@@ -0,0 +1,82 @@
+package testchado
+
+import (
+    "errors"
+    "os"
+    "strings"
+    "testing"
+)
+
+func TestLoadFasta(t *testing.T) {
+    dbm := NewTestChado(t)
+    _ = dbm.LoadDefaultFixture()
+    gff, err := os.Open("testdata/sad.gff3")
+    if err != nil {
+        t.Fatal(err)
+    }
+    defer gff.Close()
+    if err := dbm.LoadGFF3(gff, "Dictyostelium discoideum"); err != nil {
+        t.Fatalf("should have loaded gff3 file: %s", err)
+    }
+    fh, err := os.Open("testdata/sad.fasta")
+    if err != nil {
+        t.Fatal(err)
+    }
+    defer fh.Close()
+    if err := dbm.LoadFasta(fh, "chromosome", "Dictyostelium discoideum"); err != nil {
+        t.Fatalf("should have loaded fasta file: %s", err)
+    }
+
+    type feature struct {
+        Residues    string
+        Seqlen      int
+        Md5checksum string
+        Type        string
+    }
+    f := feature{}
+    query := `
+     SELECT residues, seqlen, md5checksum, cvterm.name type FROM feature
+     JOIN cvterm ON cvterm.cvterm_id = feature.type_id WHERE uniquename = ?
+    `
+    query = dbm.DBHandle().Rebind(query)
+    if err := dbm.DBHandle().Get(&f, query, "DDB_G0267178"); err != nil {
+        t.Fatalf("should have executed the query %s", err)
+    }
+    if f.Residues != "ATGAAATTTTAG" || f.Seqlen != 12 || f.Type != "gene" {
+        t.Errorf("should have updated the gene with its residues, got %v", f)
+    }
+    if f.Md5checksum != "235c52fe47c4c2121ca5f17000603847" {
+        t.Errorf("should have set the md5 checksum, got %s", f.Md5checksum)
+    }
+    if err := dbm.DBHandle().Get(&f, query, "DDB0232428"); err != nil {
+        t.Fatalf("should have executed the query %s", err)
+    }
+    if f.Seqlen != 12 || f.Type != "region" {
+        t.Errorf("should have updated the landmark with its residues, got %v", f)
+    }
+
+    fasta := ">DDB0191438\nATG\n"
+    if err := dbm.LoadFasta(strings.NewReader(fasta), "SO:0000234", "dicty"); err != nil {
+        t.Fatalf("should have loaded fasta file: %s", err)
+    }
+    if err := dbm.DBHandle().Get(&f, query, "DDB0191438"); err != nil {
+        t.Fatalf("should have executed the query %s", err)
+    }
+    if f.Seqlen != 3 || f.Type != "mRNA" {
+        t.Errorf("should have created mRNA feature with its residues, got %v", f)
+    }
+}
+
+func TestLoadFastaError(t *testing.T) {
+    dbm := NewTestChado(t)
+    _ = dbm.LoadDefaultFixture()
+    var perr *ParseError
+    err := dbm.LoadFasta(strings.NewReader("ATG\n>seq1\nATG\n"), "chromosome", "dicty")
+    if !errors.As(err, &perr) || perr.Line != 1 {
+        t.Errorf("should have reported sequence without header: %s", err)
+    }
+    err = dbm.LoadFasta(strings.NewReader(">seq1\nATG\n>seq2\nATG\n"), "chromosomes", "dicty")
+    if !errors.As(err, &perr) || perr.Line != 1 {
+        t.Errorf("should have reported unknown type: %s", err)
+    }
+}
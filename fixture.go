@@ -0,0 +1,89 @@
+package testchado
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+
+	"github.com/dictybase/testchado/internal/chadodb"
+	"github.com/jmoiron/sqlx"
+	"gopkg.in/yaml.v3"
+)
+
+// RowError reports a row of a structured fixture that could not be loaded
+type RowError struct {
+	// Name of the fixture file
+	Source string
+	// Table of the row
+	Table string
+	// Position of the row within the rows of its table, starting at 1
+	Row int
+	Err error
+}
+
+func (e *RowError) Error() string {
+	return fmt.Sprintf("%s: %s row %d: %s", e.Source, e.Table, e.Row, e.Err)
+}
+
+func (e *RowError) Unwrap() error {
+	return e.Err
+}
+
+// ParseError reports a line of a data file, for example GFF3, that could not be
+// loaded
+type ParseError struct {
+	// Format of the data file
+	Format string
+	// Line of the data file, starting at 1
+	Line int
+	Err  error
+}
+
+func (e *ParseError) Error() string {
+	return fmt.Sprintf("%s line %d: %s", e.Format, e.Line, e.Err)
+}
+
+func (e *ParseError) Unwrap() error {
+	return e.Err
+}
+
+// A structured fixture is a list of tables, every table is a map of its name to a
+// list of rows. The tables are loaded in the order they appear.
+type structuredFixture []map[string][]map[string]interface{}
+
+func parseYAMLFixture(content []byte) (structuredFixture, error) {
+	var f structuredFixture
+	err := yaml.Unmarshal(content, &f)
+	return f, err
+}
+
+func parseJSONFixture(content []byte) (structuredFixture, error) {
+	var f structuredFixture
+	dec := json.NewDecoder(bytes.NewReader(content))
+	dec.UseNumber()
+	err := dec.Decode(&f)
+	return f, err
+}
+
+// Inserts all rows of a structured fixture. References in foreign key columns
+// are resolved to the primary key of the referenced rows.
+func loadStructured(tx *sqlx.Tx, source string, f structuredFixture) error {
+	db := chadodb.New(tx)
+	for _, tables := range f {
+		if len(tables) != 1 {
+			return fmt.Errorf("%s: expected a single table per entry, got %d", source, len(tables))
+		}
+		for table, rows := range tables {
+			for i, row := range rows {
+				resolved, err := db.Row(table, row)
+				if err != nil {
+					return &RowError{Source: source, Table: table, Row: i + 1, Err: err}
+				}
+				if _, err := db.Insert(table, resolved); err != nil {
+					return &RowError{Source: source, Table: table, Row: i + 1, Err: err}
+				}
+			}
+		}
+	}
+	return nil
+}
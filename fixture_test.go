@@ -0,0 +1,74 @@
+package testchado
+
+import (
+    "errors"
+    "os"
+    "path/filepath"
+    "testing"
+)
+
+func TestLoadStructuredFixture(t *testing.T) {
+    dbm := NewTestChado(t)
+    _ = dbm.LoadDefaultFixture()
+    if err := dbm.LoadCustomFixture("testdata/sada.yaml"); err != nil {
+        t.Fatalf("should have loaded yaml fixture: %s", err)
+    }
+    if err := dbm.LoadCustomFixture("testdata/sada.json"); err != nil {
+        t.Fatalf("should have loaded json fixture: %s", err)
+    }
+
+    type entries struct{ Counter int }
+    e := entries{}
+    sqlx := dbm.DBHandle()
+    query := `
+     SELECT count(*) counter FROM feature_relationship fr
+     JOIN feature subject ON subject.feature_id = fr.subject_id
+     JOIN feature object ON object.feature_id = fr.object_id
+     JOIN cvterm ON cvterm.cvterm_id = subject.type_id
+     WHERE subject.uniquename = 'DDB0191438' AND object.uniquename = 'DDB_G0288511'
+     AND cvterm.name = 'mRNA'
+    `
+    if err := sqlx.Get(&e, query); err != nil {
+        t.Errorf("should have executed the query %s", err)
+    }
+    if e.Counter != 1 {
+        t.Error("should have loaded the mRNA part_of gene relationship")
+    }
+    query = `
+     SELECT count(*) counter FROM featureprop JOIN feature ON feature.feature_id = featureprop.feature_id
+     JOIN organism ON organism.organism_id = feature.organism_id
+     WHERE feature.uniquename = 'DDB_G0288512' AND featureprop.value = 'sadB' AND organism.common_name = 'dicty'
+    `
+    if err := sqlx.Get(&e, query); err != nil {
+        t.Errorf("should have executed the query %s", err)
+    }
+    if e.Counter != 1 {
+        t.Error("should have loaded the featureprop")
+    }
+}
+
+func TestStructuredFixtureError(t *testing.T) {
+    dbm := NewTestChado(t)
+    _ = dbm.LoadDefaultFixture()
+    fixture := filepath.Join(t.TempDir(), "broken.yml")
+    content := `
+- feature:
+    - uniquename: DDB_G0288511
+      organism: Dictyostelium discoideum
+      type: sequence:gene
+    - uniquename: DDB_G0288512
+      organism: Homo erectus
+      type: sequence:gene
+`
+    if err := os.WriteFile(fixture, []byte(content), 0644); err != nil {
+        t.Fatal(err)
+    }
+    err := dbm.LoadCustomFixture(fixture)
+    var rerr *RowError
+    if !errors.As(err, &rerr) {
+        t.Fatalf("should have returned a row error: %s", err)
+    }
+    if rerr.Table != "feature" || rerr.Row != 2 {
+        t.Errorf("should have reported second feature row, got %s", rerr)
+    }
+}
@@ -0,0 +1,256 @@
+package testchado
+
+import (
+	"bufio"
+	"database/sql"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/dictybase/testchado/internal/chadodb"
+	"github.com/jmoiron/sqlx"
+)
+
+// An annotation line of a GAF file
+type gafAnnotation struct {
+	line       int
+	object     string
+	qualifiers []string
+	goID       string
+	references []string
+	evidence   string
+	with       string
+	date       string
+	assignedBy string
+	// NCBI taxon id of the annotated object
+	taxon string
+}
+
+// Parses the annotations of a GAF 2.x file
+func parseGAF(r io.Reader) ([]*gafAnnotation, error) {
+	var annotations []*gafAnnotation
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 16*1024*1024)
+	line := 0
+	for scanner.Scan() {
+		line++
+		text := strings.TrimRight(scanner.Text(), "\r")
+		if len(strings.TrimSpace(text)) == 0 || strings.HasPrefix(text, "!") {
+			continue
+		}
+		fields := strings.Split(text, "\t")
+		if len(fields) < 15 {
+			return nil, &ParseError{Format: "gaf", Line: line, Err: fmt.Errorf("expected at least 15 tab separated columns, got %d", len(fields))}
+		}
+		a := &gafAnnotation{
+			line:       line,
+			object:     fields[1],
+			goID:       fields[4],
+			references: gafList(fields[5]),
+			evidence:   fields[6],
+			with:       fields[7],
+			date:       fields[13],
+			assignedBy: fields[14],
+		}
+		a.qualifiers = gafList(fields[3])
+		if taxa := gafList(fields[12]); len(taxa) > 0 {
+			a.taxon = strings.TrimPrefix(taxa[0], "taxon:")
+		}
+		if len(a.references) == 0 {
+			return nil, &ParseError{Format: "gaf", Line: line, Err: fmt.Errorf("annotation without reference")}
+		}
+		annotations = append(annotations, a)
+	}
+	return annotations, scanner.Err()
+}
+
+// Splits a pipe separated column
+func gafList(column string) []string {
+	var values []string
+	for _, v := range strings.Split(column, "|") {
+		if v = strings.TrimSpace(v); len(v) > 0 {
+			values = append(values, v)
+		}
+	}
+	return values
+}
+
+// Loads the annotations of a GAF 2.x file as feature_cvterm. The columns are
+// mapped as follows
+//
+//	DB Object ID   uniquename of the feature, along with the Taxon if several
+//	               organisms have one
+//	GO ID          cvterm, by its DB:accession
+//	Qualifier      NOT sets is_not, others become a qualifier feature_cvtermprop
+//	DB:Reference   pub of the feature_cvterm, the rest in feature_cvterm_pub
+//	Evidence Code  feature_cvtermprop typed by the eco term with the code as synonym
+//	With, Date     with and date feature_cvtermprop
+//	Assigned By    source feature_cvtermprop
+//
+// The features and GO terms have to be loaded and the evidence codes are resolved
+// through the eco preset. Publications are created along with a pub_dbxref if
+// needed. The whole file is loaded in a single transaction, an annotation with
+// an unresolved reference is returned as *ParseError.
+func (dbh *DBHelper) LoadGAF(r io.Reader) error {
+	if !dbh.hasLoadedSchema {
+		return ErrSchemaNotLoaded
+	}
+	annotations, err := parseGAF(r)
+	if err != nil {
+		return err
+	}
+	tx, err := dbh.dbhandler.Beginx()
+	if err != nil {
+		return err
+	}
+	db := chadodb.New(tx)
+	for _, a := range annotations {
+		if err := loadAnnotation(db, a); err != nil {
+			tx.Rollback()
+			return &ParseError{Format: "gaf", Line: a.line, Err: err}
+		}
+	}
+	return tx.Commit()
+}
+
+// Returns the feature of the DB Object ID of an annotation. Uniquenames are only
+// unique per organism and type in chado, features of several organisms are
+// narrowed down to the one with the taxon of the annotation as NCBITaxon or taxon
+// dbxref.
+func gafFeature(db *chadodb.DB, a *gafAnnotation) (int64, error) {
+	var ids []int64
+	err := sqlx.Select(db, &ids, db.Rebind("SELECT feature_id FROM feature WHERE uniquename = ? ORDER BY feature_id"), a.object)
+	if err != nil {
+		return 0, err
+	}
+	if len(ids) > 1 && len(a.taxon) > 0 {
+		var taxonIDs []int64
+		err := sqlx.Select(db, &taxonIDs, db.Rebind(`
+            SELECT feature.feature_id FROM feature
+            JOIN organism_dbxref od ON od.organism_id = feature.organism_id
+            JOIN dbxref ON dbxref.dbxref_id = od.dbxref_id
+            JOIN db ON db.db_id = dbxref.db_id
+            WHERE feature.uniquename = ? AND db.name IN ('NCBITaxon', 'taxon') AND dbxref.accession = ?
+            ORDER BY feature.feature_id
+            `), a.object, a.taxon)
+		if err != nil {
+			return 0, err
+		}
+		if len(taxonIDs) > 0 {
+			ids = taxonIDs
+		}
+	}
+	switch len(ids) {
+	case 0:
+		return 0, fmt.Errorf("unable to resolve feature %s", a.object)
+	case 1:
+		return ids[0], nil
+	}
+	return 0, fmt.Errorf("feature %s of taxon %s is ambiguous, %d features match", a.object, a.taxon, len(ids))
+}
+
+func loadAnnotation(db *chadodb.DB, a *gafAnnotation) error {
+	featureID, err := gafFeature(db, a)
+	if err != nil {
+		return err
+	}
+	cvtermID, err := db.Resolve("cvterm", a.goID)
+	if err != nil {
+		return err
+	}
+	evidenceID, err := evidenceCode(db, a.evidence)
+	if err != nil {
+		return err
+	}
+	var pubs []int64
+	for _, ref := range a.references {
+		pubID, err := db.EnsurePub(ref)
+		if err != nil {
+			return err
+		}
+		pubs = append(pubs, pubID)
+	}
+	row := map[string]interface{}{
+		"feature_id": featureID,
+		"cvterm_id":  cvtermID,
+		"pub_id":     pubs[0],
+	}
+	// the same term could be annotated with several evidence codes
+	var rank int
+	err = db.QueryRowx(
+		db.Rebind("SELECT count(*) FROM feature_cvterm WHERE feature_id = ? AND cvterm_id = ? AND pub_id = ?"),
+		featureID, cvtermID, pubs[0],
+	).Scan(&rank)
+	if err != nil {
+		return err
+	}
+	row["rank"] = rank
+	var qualifiers []string
+	for _, q := range a.qualifiers {
+		if q == "NOT" {
+			row["is_not"] = true
+			continue
+		}
+		qualifiers = append(qualifiers, q)
+	}
+	fcID, err := db.Insert("feature_cvterm", row)
+	if err != nil {
+		return err
+	}
+	if _, err := db.Insert("feature_cvtermprop", map[string]interface{}{
+		"feature_cvterm_id": fcID,
+		"type_id":           evidenceID,
+		"value":             a.evidence,
+	}); err != nil {
+		return err
+	}
+	props := map[string][]string{
+		"qualifier": qualifiers,
+		"with":      gafList(a.with),
+		"date":      gafList(a.date),
+		"source":    gafList(a.assignedBy),
+	}
+	for _, name := range []string{"qualifier", "with", "date", "source"} {
+		if len(props[name]) == 0 {
+			continue
+		}
+		typeID, err := db.EnsureCvterm("gene_ontology_association", name)
+		if err != nil {
+			return err
+		}
+		for rank, value := range props[name] {
+			if _, err := db.Insert("feature_cvtermprop", map[string]interface{}{
+				"feature_cvterm_id": fcID,
+				"type_id":           typeID,
+				"value":             value,
+				"rank":              rank,
+			}); err != nil {
+				return err
+			}
+		}
+	}
+	for _, pubID := range pubs[1:] {
+		if _, err := db.Ensure("feature_cvterm_pub", map[string]interface{}{
+			"feature_cvterm_id": fcID,
+			"pub_id":            pubID,
+		}, nil); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Returns the term of the eco cv that has the evidence code as synonym
+func evidenceCode(db *chadodb.DB, code string) (int64, error) {
+	var id int64
+	err := db.QueryRowx(db.Rebind(`
+        SELECT cvterm.cvterm_id FROM cvterm
+        JOIN cv ON cv.cv_id = cvterm.cv_id
+        JOIN cvtermsynonym syn ON syn.cvterm_id = cvterm.cvterm_id
+        WHERE cv.name = 'eco' AND syn.synonym = ?
+        `), code).Scan(&id)
+	if err == sql.ErrNoRows {
+		return 0, fmt.Errorf("unable to resolve evidence code %s, the eco preset might not be loaded", code)
+	}
+	return id, err
+}
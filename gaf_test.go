@@ -0,0 +1,178 @@
+package testchado
+
+import (
+    "errors"
+    "os"
+    "path/filepath"
+    "strings"
+    "testing"
+)
+
+// Loads the eco preset along with the GO terms and a gene to annotate
+func setupGAF(t *testing.T) Chado {
+    dbm := NewTestChado(t)
+    if err := dbm.LoadPresetFixture("eco"); err != nil {
+        t.Fatalf("should have loaded eco preset: %s", err)
+    }
+    fh, err := os.Open("testdata/go.obo")
+    if err != nil {
+        t.Fatal(err)
+    }
+    defer fh.Close()
+    if err := dbm.LoadOntology(fh); err != nil {
+        t.Fatalf("should have loaded obo file: %s", err)
+    }
+    fixture := filepath.Join(t.TempDir(), "gene.yaml")
+    content := `
+- organism:
+    - genus: Dictyostelium
+      species: discoideum
+- feature:
+    - uniquename: DDB_G0267178
+      organism: Dictyostelium discoideum
+      type: sequence:gene
+`
+    if err := os.WriteFile(fixture, []byte(content), 0644); err != nil {
+        t.Fatal(err)
+    }
+    if err := dbm.LoadCustomFixture(fixture); err != nil {
+        t.Fatalf("should have loaded gene fixture: %s", err)
+    }
+    return dbm
+}
+
+func TestLoadGAF(t *testing.T) {
+    dbm := setupGAF(t)
+    fh, err := os.Open("testdata/sad.gaf")
+    if err != nil {
+        t.Fatal(err)
+    }
+    defer fh.Close()
+    if err := dbm.LoadGAF(fh); err != nil {
+        t.Fatalf("should have loaded gaf file: %s", err)
+    }
+
+    type entries struct{ Counter int }
+    counts := map[string]string{
+        "feature_cvterm": "SELECT count(*) counter FROM feature_cvterm",
+        "is_not":         "SELECT count(*) counter FROM feature_cvterm WHERE is_not = true",
+        "ranked":         "SELECT count(*) counter FROM feature_cvterm WHERE rank = 1",
+        "evidence":       "SELECT count(*) counter FROM feature_cvtermprop fcp JOIN cvterm ON cvterm.cvterm_id = fcp.type_id JOIN cv ON cv.cv_id = cvterm.cv_id WHERE cv.name = 'eco'",
+        "ida":            "SELECT count(*) counter FROM feature_cvtermprop fcp JOIN cvterm ON cvterm.cvterm_id = fcp.type_id WHERE cvterm.name = 'direct assay evidence used in manual assertion' AND fcp.value = 'IDA'",
+        "qualifier":      "SELECT count(*) counter FROM feature_cvtermprop WHERE value = 'colocalizes_with'",
+        "with":           "SELECT count(*) counter FROM feature_cvtermprop WHERE value = 'UniProtKB:Q55GH6'",
+        "pub":            "SELECT count(*) counter FROM pub JOIN pub_dbxref ON pub_dbxref.pub_id = pub.pub_id",
+        "pmid":           "SELECT count(*) counter FROM pub_dbxref JOIN dbxref ON dbxref.dbxref_id = pub_dbxref.dbxref_id JOIN db ON db.db_id = dbxref.db_id WHERE db.name = 'PMID'",
+        "cvterm_pub":     "SELECT count(*) counter FROM feature_cvterm_pub",
+    }
+    expected := map[string]int{
+        "feature_cvterm": 3,
+        "is_not":         1,
+        "ranked":         1,
+        "evidence":       3,
+        "ida":            1,
+        "qualifier":      1,
+        "with":           1,
+        "pub":            3,
+        "pmid":           2,
+        "cvterm_pub":     1,
+    }
+    for name, query := range counts {
+        e := entries{}
+        if err := dbm.DBHandle().Get(&e, query); err != nil {
+            t.Errorf("should have executed the %s query %s", name, err)
+        }
+        if e.Counter != expected[name] {
+            t.Errorf("should have %d %s rows, got %d", expected[name], name, e.Counter)
+        }
+    }
+}
+
+func TestLoadGAFError(t *testing.T) {
+    dbm := setupGAF(t)
+    gaf := []string{
+        "dictyBase\tDDB_G0267178\tsadA\t\tGO:0005515\tPMID:12345\tIDA\t\tF\t\t\tgene\ttaxon:44689\t20140418\tdictyBase",
+        "dictyBase\tDDB_G0267178\tsadA\t\tGO:0099999\tPMID:12345\tIDA\t\tF\t\t\tgene\ttaxon:44689\t20140418\tdictyBase",
+    }
+    var perr *ParseError
+    err := dbm.LoadGAF(strings.NewReader(strings.Join(gaf, "\n")))
+    if !errors.As(err, &perr) || perr.Line != 2 {
+        t.Errorf("should have reported unresolved GO id at line 2: %s", err)
+    }
+    gaf[1] = strings.Replace(gaf[0], "IDA", "XYZ", 1)
+    err = dbm.LoadGAF(strings.NewReader(strings.Join(gaf, "\n")))
+    if err == nil || !strings.Contains(err.Error(), "evidence code XYZ") {
+        t.Errorf("should have reported unresolved evidence code: %s", err)
+    }
+    gaf[1] = strings.Replace(gaf[0], "DDB_G0267178", "DDB_G0000000", 1)
+    err = dbm.LoadGAF(strings.NewReader(strings.Join(gaf, "\n")))
+    if err == nil || !strings.Contains(err.Error(), "feature DDB_G0000000") {
+        t.Errorf("should have reported unresolved feature: %s", err)
+    }
+
+    type entries struct{ Counter int }
+    e := entries{}
+    if err := dbm.DBHandle().Get(&e, "SELECT count(*) counter FROM feature_cvterm"); err != nil {
+        t.Errorf("should have executed the query %s", err)
+    }
+    if e.Counter != 0 {
+        t.Error("should have rolled back the gaf file")
+    }
+}
+
+func TestLoadGAFAmbiguousFeature(t *testing.T) {
+    dbm := setupGAF(t)
+    fixture := filepath.Join(t.TempDir(), "purpureum.yaml")
+    content := `
+- organism:
+    - genus: Dictyostelium
+      species: purpureum
+- feature:
+    - uniquename: DDB_G0267178
+      organism: Dictyostelium purpureum
+      type: sequence:gene
+`
+    if err := os.WriteFile(fixture, []byte(content), 0644); err != nil {
+        t.Fatal(err)
+    }
+    if err := dbm.LoadCustomFixture(fixture); err != nil {
+        t.Fatalf("should have loaded gene fixture: %s", err)
+    }
+    gaf := "dictyBase\tDDB_G0267178\tsadA\t\tGO:0005515\tPMID:12345\tIDA\t\tF\t\t\tgene\ttaxon:44689\t20140418\tdictyBase"
+    err := dbm.LoadGAF(strings.NewReader(gaf))
+    if err == nil || !strings.Contains(err.Error(), "ambiguous") {
+        t.Errorf("should have reported the ambiguous feature: %s", err)
+    }
+
+    content = `
+- db:
+    - name: NCBITaxon
+- dbxref:
+    - db: NCBITaxon
+      accession: "44689"
+- organism_dbxref:
+    - organism: Dictyostelium discoideum
+      dbxref: NCBITaxon:44689
+`
+    if err := os.WriteFile(fixture, []byte(content), 0644); err != nil {
+        t.Fatal(err)
+    }
+    if err := dbm.LoadCustomFixture(fixture); err != nil {
+        t.Fatalf("should have loaded taxon fixture: %s", err)
+    }
+    if err := dbm.LoadGAF(strings.NewReader(gaf)); err != nil {
+        t.Fatalf("should have narrowed the feature down by taxon: %s", err)
+    }
+    type entries struct{ Counter int }
+    e := entries{}
+    q := `SELECT count(*) counter FROM feature_cvterm fc
+        JOIN feature ON feature.feature_id = fc.feature_id
+        JOIN organism ON organism.organism_id = feature.organism_id
+        WHERE organism.species = 'discoideum'`
+    if err := dbm.DBHandle().Get(&e, q); err != nil {
+        t.Errorf("should have executed the query %s", err)
+    }
+    if e.Counter != 1 {
+        t.Errorf("should have annotated the discoideum gene, got %d", e.Counter)
+    }
+}
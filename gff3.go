@@ -0,0 +1,399 @@
+package testchado
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/dictybase/testchado/internal/chadodb"
+)
+
+// A feature line of a GFF3 file
+type gffFeature struct {
+	line   int
+	seqid  string
+	ftype  string
+	start  int
+	end    int
+	strand interface{}
+	phase  interface{}
+	attrs  []gffAttr
+}
+
+// An attribute of a GFF3 feature, multiple values are separated by comma
+type gffAttr struct {
+	tag    string
+	values []string
+}
+
+func (f *gffFeature) attr(tag string) []string {
+	for _, a := range f.attrs {
+		if a.tag == tag {
+			return a.values
+		}
+	}
+	return nil
+}
+
+// The landmarks declared by ##sequence-region pragmas, seqid => length
+type gffRegions map[string]int
+
+// Parses the feature lines of a GFF3 file, anything after the ##FASTA
+// directive is ignored
+func parseGFF3(r io.Reader) ([]*gffFeature, gffRegions, error) {
+	var features []*gffFeature
+	regions := make(gffRegions)
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 16*1024*1024)
+	line := 0
+	for scanner.Scan() {
+		line++
+		text := strings.TrimRight(scanner.Text(), "\r")
+		switch {
+		case strings.HasPrefix(text, "##FASTA"), strings.HasPrefix(text, ">"):
+			return features, regions, nil
+		case strings.HasPrefix(text, "##sequence-region"):
+			fields := strings.Fields(text)
+			if len(fields) == 4 {
+				if end, err := strconv.Atoi(fields[3]); err == nil {
+					regions[fields[1]] = end
+				}
+			}
+			continue
+		case len(strings.TrimSpace(text)) == 0, strings.HasPrefix(text, "#"):
+			continue
+		}
+		f, err := parseGFFLine(text)
+		if err != nil {
+			return nil, nil, &ParseError{Format: "gff3", Line: line, Err: err}
+		}
+		f.line = line
+		features = append(features, f)
+	}
+	return features, regions, scanner.Err()
+}
+
+func parseGFFLine(text string) (*gffFeature, error) {
+	fields := strings.Split(text, "\t")
+	if len(fields) != 9 {
+		return nil, fmt.Errorf("expected 9 tab separated columns, got %d", len(fields))
+	}
+	f := &gffFeature{seqid: gffUnescape(fields[0]), ftype: gffUnescape(fields[2])}
+	var err error
+	if f.start, err = strconv.Atoi(fields[3]); err != nil {
+		return nil, fmt.Errorf("invalid start %s", fields[3])
+	}
+	if f.end, err = strconv.Atoi(fields[4]); err != nil {
+		return nil, fmt.Errorf("invalid end %s", fields[4])
+	}
+	if f.start > f.end {
+		return nil, fmt.Errorf("start %d is after end %d", f.start, f.end)
+	}
+	switch fields[6] {
+	case "+":
+		f.strand = 1
+	case "-":
+		f.strand = -1
+	case ".", "?":
+	default:
+		return nil, fmt.Errorf("invalid strand %s", fields[6])
+	}
+	if fields[7] != "." {
+		phase, err := strconv.Atoi(fields[7])
+		if err != nil || phase < 0 || phase > 2 {
+			return nil, fmt.Errorf("invalid phase %s", fields[7])
+		}
+		f.phase = phase
+	}
+	if fields[8] == "." {
+		return f, nil
+	}
+	for _, pair := range strings.Split(fields[8], ";") {
+		if len(strings.TrimSpace(pair)) == 0 {
+			continue
+		}
+		kv := strings.SplitN(pair, "=", 2)
+		if len(kv) != 2 {
+			return nil, fmt.Errorf("attribute %s is not in tag=value format", pair)
+		}
+		a := gffAttr{tag: gffUnescape(strings.TrimSpace(kv[0]))}
+		for _, v := range strings.Split(kv[1], ",") {
+			a.values = append(a.values, gffUnescape(v))
+		}
+		f.attrs = append(f.attrs, a)
+	}
+	return f, nil
+}
+
+// Decodes the percent encoded characters of a GFF3 column
+func gffUnescape(s string) string {
+	if u, err := url.PathUnescape(s); err == nil {
+		return u
+	}
+	return s
+}
+
+// Loads the features of a GFF3 file in the chado schema
+type gffLoader struct {
+	db       *chadodb.DB
+	organism int64
+	regions  gffRegions
+	// GFF3 ID or generated uniquename => feature_id
+	features map[string]int64
+	types    map[string]int64
+}
+
+// Loads the features of a GFF3 file for an organism, given as its genus and
+// species separated by space or its common name. The features are linked
+// within the chado schema as follows
+//
+//	type           cvterm of the sequence ontology, by name or SO accession
+//	seqid          srcfeature of the featureloc, created as region if missing
+//	ID             uniquename of the feature, generated if absent
+//	Name           name of the feature
+//	Parent         part_of feature_relationship
+//	Derives_from   derives_from feature_relationship
+//	Dbxref         feature_dbxref, the dbxref is created if needed
+//	Ontology_term  feature_cvterm, the cvterm has to exist
+//	others         featureprop typed by a cvterm of the feature_property cv
+//
+// Features spanning multiple lines, for example CDS, get one featureloc per
+// line. The whole file is loaded in a single transaction, a failing line is
+// returned as *ParseError.
+func (dbh *DBHelper) LoadGFF3(r io.Reader, organism string) error {
+	if !dbh.hasLoadedSchema {
+		return ErrSchemaNotLoaded
+	}
+	features, regions, err := parseGFF3(r)
+	if err != nil {
+		return err
+	}
+	tx, err := dbh.dbhandler.Beginx()
+	if err != nil {
+		return err
+	}
+	db := chadodb.New(tx)
+	organismID, err := db.Resolve("organism", organism)
+	if err == nil {
+		l := &gffLoader{
+			db:       db,
+			organism: organismID,
+			regions:  regions,
+			features: make(map[string]int64),
+			types:    make(map[string]int64),
+		}
+		err = l.load(features)
+	}
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+	return tx.Commit()
+}
+
+func (l *gffLoader) load(features []*gffFeature) error {
+	// the features are created first as any line could refer to the ones after
+	ids := make([]int64, len(features))
+	seen := make(map[int64]bool)
+	for i, f := range features {
+		id, err := l.createFeature(f)
+		if err != nil {
+			return &ParseError{Format: "gff3", Line: f.line, Err: err}
+		}
+		ids[i] = id
+	}
+	ranks := make(map[int64]int)
+	for i, f := range features {
+		if err := l.createLoc(f, ids[i], ranks[ids[i]]); err != nil {
+			return &ParseError{Format: "gff3", Line: f.line, Err: err}
+		}
+		ranks[ids[i]]++
+		// lines of the same feature repeat the attributes
+		if seen[ids[i]] {
+			continue
+		}
+		seen[ids[i]] = true
+		if err := l.createAttrs(f, ids[i]); err != nil {
+			return &ParseError{Format: "gff3", Line: f.line, Err: err}
+		}
+	}
+	return nil
+}
+
+func (l *gffLoader) createFeature(f *gffFeature) (int64, error) {
+	uniquename := fmt.Sprintf("%s:%s:%d..%d", f.seqid, f.ftype, f.start, f.end)
+	if ids := f.attr("ID"); len(ids) > 0 {
+		if id, ok := l.features[ids[0]]; ok {
+			return id, nil
+		}
+		uniquename = ids[0]
+	} else if _, ok := l.features[uniquename]; ok {
+		uniquename = fmt.Sprintf("%s:%d", uniquename, f.line)
+	}
+	typeID, err := l.soType(f.ftype)
+	if err != nil {
+		return 0, err
+	}
+	row := map[string]interface{}{
+		"organism_id": l.organism,
+		"uniquename":  uniquename,
+		"type_id":     typeID,
+	}
+	if names := f.attr("Name"); len(names) > 0 {
+		row["name"] = names[0]
+	}
+	id, err := l.db.Insert("feature", row)
+	if err != nil {
+		return 0, err
+	}
+	l.features[uniquename] = id
+	return id, nil
+}
+
+func (l *gffLoader) createLoc(f *gffFeature, id int64, rank int) error {
+	// a landmark is not located on itself
+	if ids := f.attr("ID"); len(ids) > 0 && ids[0] == f.seqid {
+		return nil
+	}
+	srcID, err := l.feature(f.seqid)
+	if errors.Is(err, chadodb.ErrNotFound) {
+		srcID, err = l.createLandmark(f.seqid)
+	}
+	if err != nil {
+		return err
+	}
+	_, err = l.db.Insert("featureloc", map[string]interface{}{
+		"feature_id":    id,
+		"srcfeature_id": srcID,
+		"fmin":          f.start - 1,
+		"fmax":          f.end,
+		"strand":        f.strand,
+		"phase":         f.phase,
+		"rank":          rank,
+	})
+	return err
+}
+
+func (l *gffLoader) createLandmark(seqid string) (int64, error) {
+	typeID, err := l.soType("region")
+	if err != nil {
+		return 0, err
+	}
+	row := map[string]interface{}{
+		"organism_id": l.organism,
+		"uniquename":  seqid,
+		"name":        seqid,
+		"type_id":     typeID,
+	}
+	if seqlen, ok := l.regions[seqid]; ok {
+		row["seqlen"] = seqlen
+	}
+	id, err := l.db.Insert("feature", row)
+	if err != nil {
+		return 0, err
+	}
+	l.features[seqid] = id
+	return id, nil
+}
+
+func (l *gffLoader) createAttrs(f *gffFeature, id int64) error {
+	for _, a := range f.attrs {
+		for rank, v := range a.values {
+			var err error
+			switch a.tag {
+			case "ID", "Name":
+			case "Parent":
+				err = l.createRelationship(id, v, "part_of")
+			case "Derives_from":
+				err = l.createRelationship(id, v, "derives_from")
+			case "Dbxref":
+				var dbxrefID int64
+				if dbxrefID, err = l.db.EnsureDbxref(v); err == nil {
+					_, err = l.db.Ensure("feature_dbxref", map[string]interface{}{"feature_id": id, "dbxref_id": dbxrefID}, nil)
+				}
+			case "Ontology_term":
+				err = l.createFeatureCvterm(id, v)
+			default:
+				var typeID int64
+				if typeID, err = l.db.EnsureCvterm("feature_property", a.tag); err == nil {
+					_, err = l.db.Insert("featureprop", map[string]interface{}{
+						"feature_id": id,
+						"type_id":    typeID,
+						"value":      v,
+						"rank":       rank,
+					})
+				}
+			}
+			if err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func (l *gffLoader) createRelationship(subjectID int64, object, relation string) error {
+	objectID, err := l.feature(object)
+	if err != nil {
+		return fmt.Errorf("unable to resolve %s feature %s: %s", relation, object, err)
+	}
+	typeID, err := l.db.EnsureCvterm("relationship", relation)
+	if err != nil {
+		return err
+	}
+	_, err = l.db.Insert("feature_relationship", map[string]interface{}{
+		"subject_id": subjectID,
+		"object_id":  objectID,
+		"type_id":    typeID,
+	})
+	return err
+}
+
+func (l *gffLoader) createFeatureCvterm(id int64, term string) error {
+	cvtermID, err := l.db.Resolve("cvterm", term)
+	if err != nil {
+		return err
+	}
+	pubID, err := l.db.NullPub()
+	if err != nil {
+		return err
+	}
+	_, err = l.db.Ensure("feature_cvterm", map[string]interface{}{
+		"feature_id": id,
+		"cvterm_id":  cvtermID,
+		"pub_id":     pubID,
+	}, nil)
+	return err
+}
+
+// Returns a feature of the file or an existing one of the organism
+func (l *gffLoader) feature(uniquename string) (int64, error) {
+	if id, ok := l.features[uniquename]; ok {
+		return id, nil
+	}
+	id, err := l.db.Lookup("feature", map[string]interface{}{
+		"organism_id": l.organism,
+		"uniquename":  uniquename,
+	})
+	if err == nil {
+		l.features[uniquename] = id
+	}
+	return id, err
+}
+
+// Resolves a sequence ontology term by its name or accession
+func (l *gffLoader) soType(name string) (int64, error) {
+	if id, ok := l.types[name]; ok {
+		return id, nil
+	}
+	id, err := l.db.SequenceTerm(name)
+	if err != nil {
+		return 0, err
+	}
+	l.types[name] = id
+	return id, nil
+}
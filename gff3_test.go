@@ -0,0 +1,99 @@
+package testchado
+
+import (
+    "errors"
+    "os"
+    "strings"
+    "testing"
+)
+
+func TestLoadGFF3(t *testing.T) {
+    dbm := NewTestChado(t)
+    _ = dbm.LoadDefaultFixture()
+    fh, err := os.Open("testdata/sad.gff3")
+    if err != nil {
+        t.Fatal(err)
+    }
+    defer fh.Close()
+    if err := dbm.LoadGFF3(fh, "Dictyostelium discoideum"); err != nil {
+        t.Fatalf("should have loaded gff3 file: %s", err)
+    }
+
+    type entries struct{ Counter int }
+    counts := map[string]string{
+        "feature":        "SELECT count(*) counter FROM feature",
+        "featureloc":     "SELECT count(*) counter FROM featureloc",
+        "part_of":        "SELECT count(*) counter FROM feature_relationship fr JOIN cvterm ON cvterm.cvterm_id = fr.type_id WHERE cvterm.name = 'part_of'",
+        "derives_from":   "SELECT count(*) counter FROM feature_relationship fr JOIN cvterm ON cvterm.cvterm_id = fr.type_id WHERE cvterm.name = 'derives_from'",
+        "feature_dbxref": "SELECT count(*) counter FROM feature_dbxref JOIN dbxref ON dbxref.dbxref_id = feature_dbxref.dbxref_id WHERE dbxref.accession = 'Q55GH6'",
+        "feature_cvterm": "SELECT count(*) counter FROM feature_cvterm",
+        "featureprop":    "SELECT count(*) counter FROM featureprop WHERE value IN ('sad gene', 'two alleles')",
+        "landmark":       "SELECT count(*) counter FROM feature WHERE uniquename = 'DDB0232428' AND seqlen = 8467578",
+    }
+    expected := map[string]int{
+        "feature":        7,
+        "featureloc":     7,
+        "part_of":        4,
+        "derives_from":   1,
+        "feature_dbxref": 1,
+        "feature_cvterm": 1,
+        "featureprop":    2,
+        "landmark":       1,
+    }
+    for name, query := range counts {
+        e := entries{}
+        if err := dbm.DBHandle().Get(&e, query); err != nil {
+            t.Errorf("should have executed the %s query %s", name, err)
+        }
+        if e.Counter != expected[name] {
+            t.Errorf("should have %d %s rows, got %d", expected[name], name, e.Counter)
+        }
+    }
+
+    e := struct {
+        Fmin  int
+        Fmax  int
+        Phase int
+    }{}
+    err = dbm.DBHandle().Get(&e, `
+     SELECT fmin, fmax, phase FROM featureloc JOIN feature ON feature.feature_id = featureloc.feature_id
+     WHERE feature.uniquename = 'cds1' AND featureloc.rank = 1
+    `)
+    if err != nil {
+        t.Errorf("should have executed the query %s", err)
+    }
+    if e.Fmin != 2099 || e.Fmax != 3287 || e.Phase != 2 {
+        t.Errorf("should have located the second CDS segment in interbase coordinates, got %v", e)
+    }
+}
+
+func TestLoadGFF3Error(t *testing.T) {
+    dbm := NewTestChado(t)
+    _ = dbm.LoadDefaultFixture()
+    content := "##gff-version 3\nchr1\t.\tgene\t1\t100\t.\t+\t.\tID=g1\nchr1\t.\tmRNA\t1\t100\t.\t+\t.\tID=m1;Parent=g2\n"
+    err := dbm.LoadGFF3(strings.NewReader(content), "dicty")
+    var perr *ParseError
+    if !errors.As(err, &perr) {
+        t.Fatalf("should have returned a parse error: %s", err)
+    }
+    if perr.Line != 3 {
+        t.Errorf("should have reported line 3, got %d", perr.Line)
+    }
+    err = dbm.LoadGFF3(strings.NewReader("chr1\t.\tgene\t1\t100\n"), "dicty")
+    if !errors.As(err, &perr) {
+        t.Fatalf("should have returned a parse error: %s", err)
+    }
+    err = dbm.LoadGFF3(strings.NewReader("chr1\t.\tgenes\t1\t100\t.\t+\t.\tID=g1\n"), "dicty")
+    if err == nil || !strings.Contains(err.Error(), "unknown sequence ontology type genes") {
+        t.Errorf("should have reported unknown type: %s", err)
+    }
+
+    type entries struct{ Counter int }
+    e := entries{}
+    if err := dbm.DBHandle().Get(&e, "SELECT count(*) counter FROM feature"); err != nil {
+        t.Errorf("should have executed the query %s", err)
+    }
+    if e.Counter != 0 {
+        t.Error("should have rolled back the gff3 file")
+    }
+}
@@ -0,0 +1,613 @@
+// Package chadodb provides the lookup and insert helpers shared by the fixture
+// loaders and builders of testchado. All helpers work identically on the sqlite
+// and postgresql backends.
+package chadodb
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// Returned when a row could not be found
+var ErrNotFound = errors.New("no matching row")
+
+// DB wraps a database handle, usually a transaction, and caches the
+// structure of the chado tables
+type DB struct {
+	sqlx.Ext
+	columns map[string]map[string]string
+	fks     map[string]map[string]string
+}
+
+func New(e sqlx.Ext) *DB {
+	return &DB{
+		Ext:     e,
+		columns: make(map[string]map[string]string),
+		fks:     make(map[string]map[string]string),
+	}
+}
+
+// Name of the primary key column of a chado table
+func PrimaryKey(table string) string {
+	return table + "_id"
+}
+
+func (db *DB) isPostgres() bool {
+	return db.DriverName() == "postgres"
+}
+
+// Tables of the chado schema
+func (db *DB) Tables() ([]string, error) {
+	var tables []string
+	query := "SELECT name FROM sqlite_master WHERE type = 'table' AND name NOT LIKE 'sqlite_%' ORDER BY name"
+	if db.isPostgres() {
+		query = `
+            SELECT table_name FROM information_schema.tables
+            WHERE table_schema = current_schema() AND table_type = 'BASE TABLE' ORDER BY table_name
+            `
+	}
+	err := sqlx.Select(db, &tables, query)
+	return tables, err
+}
+
+// Columns of a table
+func (db *DB) Columns(table string) (map[string]bool, error) {
+	types, err := db.ColumnTypes(table)
+	if err != nil {
+		return nil, err
+	}
+	cols := make(map[string]bool)
+	for c := range types {
+		cols[c] = true
+	}
+	return cols, nil
+}
+
+// Columns of a table along with their declared type in lower case, for
+// example integer or boolean
+func (db *DB) ColumnTypes(table string) (map[string]string, error) {
+	if types, ok := db.columns[table]; ok {
+		return types, nil
+	}
+	type column struct {
+		Name string
+		Type string
+	}
+	var cols []column
+	if db.isPostgres() {
+		err := sqlx.Select(db, &cols, `
+            SELECT column_name "name", data_type "type" FROM information_schema.columns
+            WHERE table_schema = current_schema() AND table_name = $1
+            `, table)
+		if err != nil {
+			return nil, err
+		}
+	} else {
+		rows, err := db.Queryx("PRAGMA table_info(" + table + ")")
+		if err != nil {
+			return nil, err
+		}
+		defer rows.Close()
+		for rows.Next() {
+			m := make(map[string]interface{})
+			if err := rows.MapScan(m); err != nil {
+				return nil, err
+			}
+			cols = append(cols, column{Name: toString(m["name"]), Type: toString(m["type"])})
+		}
+		if err := rows.Err(); err != nil {
+			return nil, err
+		}
+	}
+	if len(cols) == 0 {
+		return nil, fmt.Errorf("unknown table %s", table)
+	}
+	types := make(map[string]string)
+	for _, c := range cols {
+		types[c.Name] = strings.ToLower(c.Type)
+	}
+	db.columns[table] = types
+	return types, nil
+}
+
+// Unique constraints of a table other than the primary key, every one is the
+// list of its columns
+func (db *DB) UniqueKeys(table string) ([][]string, error) {
+	type column struct {
+		Constraint string
+		Name       string
+	}
+	var cols []column
+	if db.isPostgres() {
+		err := sqlx.Select(db, &cols, `
+            SELECT c.conname "constraint", a.attname "name" FROM pg_constraint c
+            JOIN pg_class src ON src.oid = c.conrelid
+            JOIN pg_namespace n ON n.oid = src.relnamespace
+            JOIN LATERAL unnest(c.conkey) WITH ORDINALITY k(attnum, ord) ON true
+            JOIN pg_attribute a ON a.attrelid = c.conrelid AND a.attnum = k.attnum
+            WHERE c.contype = 'u' AND n.nspname = current_schema() AND src.relname = $1
+            ORDER BY c.conname, k.ord
+            `, table)
+		if err != nil {
+			return nil, err
+		}
+	} else {
+		var indexes []string
+		rows, err := db.Queryx("PRAGMA index_list(" + table + ")")
+		if err != nil {
+			return nil, err
+		}
+		for rows.Next() {
+			m := make(map[string]interface{})
+			if err := rows.MapScan(m); err != nil {
+				rows.Close()
+				return nil, err
+			}
+			if toString(m["unique"]) == "1" && toString(m["origin"]) != "pk" {
+				indexes = append(indexes, toString(m["name"]))
+			}
+		}
+		rows.Close()
+		sort.Strings(indexes)
+		for _, idx := range indexes {
+			var names []string
+			rows, err := db.Queryx("PRAGMA index_info(" + idx + ")")
+			if err != nil {
+				return nil, err
+			}
+			for rows.Next() {
+				m := make(map[string]interface{})
+				if err := rows.MapScan(m); err != nil {
+					rows.Close()
+					return nil, err
+				}
+				names = append(names, toString(m["name"]))
+			}
+			rows.Close()
+			for _, n := range names {
+				cols = append(cols, column{Constraint: idx, Name: n})
+			}
+		}
+	}
+	var keys [][]string
+	for i, c := range cols {
+		if i == 0 || cols[i-1].Constraint != c.Constraint {
+			keys = append(keys, nil)
+		}
+		keys[len(keys)-1] = append(keys[len(keys)-1], c.Name)
+	}
+	return keys, nil
+}
+
+// Foreign keys of a table, column => referenced table
+func (db *DB) ForeignKeys(table string) (map[string]string, error) {
+	if fks, ok := db.fks[table]; ok {
+		return fks, nil
+	}
+	type ref struct {
+		Column string
+		Table  string
+	}
+	var refs []ref
+	if db.isPostgres() {
+		err := sqlx.Select(db, &refs, `
+            SELECT a.attname "column", dest.relname "table" FROM pg_constraint c
+            JOIN pg_class src ON src.oid = c.conrelid
+            JOIN pg_class dest ON dest.oid = c.confrelid
+            JOIN pg_namespace n ON n.oid = src.relnamespace
+            JOIN pg_attribute a ON a.attrelid = c.conrelid AND a.attnum = c.conkey[1]
+            WHERE c.contype = 'f' AND n.nspname = current_schema() AND src.relname = $1
+            `, table)
+		if err != nil {
+			return nil, err
+		}
+	} else {
+		rows, err := db.Queryx("PRAGMA foreign_key_list(" + table + ")")
+		if err != nil {
+			return nil, err
+		}
+		defer rows.Close()
+		for rows.Next() {
+			m := make(map[string]interface{})
+			if err := rows.MapScan(m); err != nil {
+				return nil, err
+			}
+			refs = append(refs, ref{Column: toString(m["from"]), Table: toString(m["table"])})
+		}
+		if err := rows.Err(); err != nil {
+			return nil, err
+		}
+	}
+	fks := make(map[string]string)
+	for _, r := range refs {
+		fks[r.Column] = r.Table
+	}
+	db.fks[table] = fks
+	return fks, nil
+}
+
+// Inserts a row and returns its primary key
+func (db *DB) Insert(table string, row map[string]interface{}) (int64, error) {
+	var cols, binds []string
+	var args []interface{}
+	for _, c := range sortedKeys(row) {
+		cols = append(cols, c)
+		binds = append(binds, "?")
+		args = append(args, row[c])
+	}
+	query := fmt.Sprintf("INSERT INTO %s (%s) VALUES (%s)", table, strings.Join(cols, ", "), strings.Join(binds, ", "))
+	if len(cols) == 0 {
+		query = fmt.Sprintf("INSERT INTO %s DEFAULT VALUES", table)
+	}
+	if db.isPostgres() {
+		var id int64
+		err := db.QueryRowx(db.Rebind(query+" RETURNING "+PrimaryKey(table)), args...).Scan(&id)
+		if err != nil {
+			return 0, fmt.Errorf("unable to insert in %s: %s", table, err)
+		}
+		return id, nil
+	}
+	res, err := db.Exec(db.Rebind(query), args...)
+	if err != nil {
+		return 0, fmt.Errorf("unable to insert in %s: %s", table, err)
+	}
+	return res.LastInsertId()
+}
+
+// Updates the columns of a row identified by its primary key
+func (db *DB) Update(table string, id int64, row map[string]interface{}) error {
+	var sets []string
+	var args []interface{}
+	for _, c := range sortedKeys(row) {
+		sets = append(sets, c+" = ?")
+		args = append(args, row[c])
+	}
+	args = append(args, id)
+	query := fmt.Sprintf("UPDATE %s SET %s WHERE %s = ?", table, strings.Join(sets, ", "), PrimaryKey(table))
+	if _, err := db.Exec(db.Rebind(query), args...); err != nil {
+		return fmt.Errorf("unable to update %s: %s", table, err)
+	}
+	return nil
+}
+
+// Deletes a row identified by its primary key
+func (db *DB) Delete(table string, id int64) error {
+	query := fmt.Sprintf("DELETE FROM %s WHERE %s = ?", table, PrimaryKey(table))
+	if _, err := db.Exec(db.Rebind(query), id); err != nil {
+		return fmt.Errorf("unable to delete from %s: %s", table, err)
+	}
+	return nil
+}
+
+// Returns the primary key of the row matching all the given column values,
+// ErrNotFound if there is none. A nil value matches NULL.
+func (db *DB) Lookup(table string, where map[string]interface{}) (int64, error) {
+	var conds []string
+	var args []interface{}
+	for _, c := range sortedKeys(where) {
+		if where[c] == nil {
+			conds = append(conds, c+" IS NULL")
+			continue
+		}
+		conds = append(conds, c+" = ?")
+		args = append(args, where[c])
+	}
+	query := fmt.Sprintf("SELECT %s FROM %s", PrimaryKey(table), table)
+	if len(conds) > 0 {
+		query += " WHERE " + strings.Join(conds, " AND ")
+	}
+	var id int64
+	err := db.QueryRowx(db.Rebind(query), args...).Scan(&id)
+	switch {
+	case err == sql.ErrNoRows:
+		return 0, ErrNotFound
+	case err != nil:
+		return 0, err
+	}
+	return id, nil
+}
+
+// Returns the primary key of the row matching the given column values, the row
+// is inserted along with the extra column values if there is none
+func (db *DB) Ensure(table string, where, extra map[string]interface{}) (int64, error) {
+	id, err := db.Lookup(table, where)
+	if err != ErrNotFound {
+		return id, err
+	}
+	row := make(map[string]interface{})
+	for k, v := range where {
+		row[k] = v
+	}
+	for k, v := range extra {
+		row[k] = v
+	}
+	return db.Insert(table, row)
+}
+
+// Returns the dbxref of a DB:accession identifier, the db and the dbxref are
+// created if needed
+func (db *DB) EnsureDbxref(id string) (int64, error) {
+	parts := strings.SplitN(id, ":", 2)
+	if len(parts) != 2 {
+		return 0, fmt.Errorf("dbxref %s is not in DB:accession format", id)
+	}
+	dbID, err := db.Ensure("db", map[string]interface{}{"name": parts[0]}, nil)
+	if err != nil {
+		return 0, err
+	}
+	return db.Ensure("dbxref", map[string]interface{}{"db_id": dbID, "accession": parts[1]}, nil)
+}
+
+// Returns the cvterm of a cv, the cv and the cvterm are created if needed. A
+// new cvterm gets a dbxref in the internal db.
+func (db *DB) EnsureCvterm(cv, name string) (int64, error) {
+	cvID, err := db.Ensure("cv", map[string]interface{}{"name": cv}, nil)
+	if err != nil {
+		return 0, err
+	}
+	where := map[string]interface{}{"cv_id": cvID, "name": name, "is_obsolete": 0}
+	id, err := db.Lookup("cvterm", where)
+	if err != ErrNotFound {
+		return id, err
+	}
+	dbxrefID, err := db.EnsureDbxref("internal:" + cv + ":" + name)
+	if err != nil {
+		return 0, err
+	}
+	where["dbxref_id"] = dbxrefID
+	return db.Insert("cvterm", where)
+}
+
+// Returns the cvterm of an ontology, given either by the name of its cv or by
+// its db, for example sequence or SO. The cvterm is created in a cv of that name
+// if there is none.
+func (db *DB) EnsureTerm(ontology, name string) (int64, error) {
+	var ids []int64
+	err := sqlx.Select(db, &ids, db.Rebind(`
+        SELECT cvterm.cvterm_id FROM cvterm
+        JOIN cv ON cv.cv_id = cvterm.cv_id
+        JOIN dbxref ON dbxref.dbxref_id = cvterm.dbxref_id
+        JOIN db ON db.db_id = dbxref.db_id
+        WHERE cvterm.name = ? AND cvterm.is_obsolete = 0
+        AND (cv.name = ? OR db.name = ? OR dbxref.accession LIKE ?)
+        ORDER BY cvterm.cvterm_id
+        `), name, ontology, ontology, ontology+":%")
+	if err != nil {
+		return 0, err
+	}
+	if len(ids) > 0 {
+		return ids[0], nil
+	}
+	return db.EnsureCvterm(ontology, name)
+}
+
+// Returns the placeholder publication for rows that require one, it is
+// created if needed
+func (db *DB) NullPub() (int64, error) {
+	typeID, err := db.EnsureCvterm("null", "null")
+	if err != nil {
+		return 0, err
+	}
+	return db.Ensure("pub", map[string]interface{}{"uniquename": "null"}, map[string]interface{}{"type_id": typeID})
+}
+
+// Returns the publication of a DB:accession reference, for example PMID:4312,
+// through its uniquename or pub_dbxref. A missing one is created along with the
+// pub_dbxref.
+func (db *DB) EnsurePub(ref string) (int64, error) {
+	dbxrefID, err := db.EnsureDbxref(ref)
+	if err != nil {
+		return 0, err
+	}
+	var id int64
+	err = db.QueryRowx(db.Rebind("SELECT pub_id FROM pub_dbxref WHERE dbxref_id = ?"), dbxrefID).Scan(&id)
+	switch {
+	case err == nil:
+		return id, nil
+	case err != sql.ErrNoRows:
+		return 0, err
+	}
+	typeID, err := db.EnsureCvterm("pub_type", "publication")
+	if err != nil {
+		return 0, err
+	}
+	id, err = db.Ensure("pub", map[string]interface{}{"uniquename": ref}, map[string]interface{}{"type_id": typeID})
+	if err != nil {
+		return 0, err
+	}
+	_, err = db.Insert("pub_dbxref", map[string]interface{}{"pub_id": id, "dbxref_id": dbxrefID})
+	return id, err
+}
+
+// Returns the cvterm of the sequence ontology by its name or accession, for
+// example gene or SO:0000704
+func (db *DB) SequenceTerm(name string) (int64, error) {
+	id, err := db.Resolve("cvterm", "sequence:"+name)
+	if err != nil && strings.Contains(name, ":") {
+		id, err = db.Resolve("cvterm", name)
+	}
+	if err != nil {
+		return 0, fmt.Errorf("unknown sequence ontology type %s", name)
+	}
+	return id, nil
+}
+
+// Resolves a reference to a row of a table and returns its primary key. The
+// reference could be
+//   - an integer, the primary key itself
+//   - a map of column values, which could themselves be references
+//   - a string natural key, see NaturalKey
+func (db *DB) Resolve(table string, ref interface{}) (int64, error) {
+	if id, ok := toInt(ref); ok {
+		return id, nil
+	}
+	var where map[string]interface{}
+	switch r := ref.(type) {
+	case map[string]interface{}:
+		row, err := db.Row(table, r)
+		if err != nil {
+			return 0, err
+		}
+		where = row
+	case string:
+		w, err := db.NaturalKey(table, r)
+		if err != nil {
+			return 0, err
+		}
+		where = w
+	default:
+		return 0, fmt.Errorf("unsupported %s reference %v", table, ref)
+	}
+	id, err := db.Lookup(table, where)
+	if err == ErrNotFound {
+		return 0, fmt.Errorf("unable to resolve %s %v", table, ref)
+	}
+	return id, err
+}
+
+// Converts a natural key to the column values that identify a row
+//
+//	cvterm:   cv:name, for example sequence:gene, otherwise DB:accession of its dbxref
+//	dbxref:   DB:accession
+//	organism: genus and species separated by space, otherwise common name
+//	others:   uniquename if the table has one, otherwise name
+func (db *DB) NaturalKey(table, key string) (map[string]interface{}, error) {
+	switch table {
+	case "cvterm":
+		parts := strings.SplitN(key, ":", 2)
+		if len(parts) != 2 {
+			return nil, fmt.Errorf("cvterm %s is not in cv:name or DB:accession format", key)
+		}
+		if cvID, err := db.Lookup("cv", map[string]interface{}{"name": parts[0]}); err == nil {
+			where := map[string]interface{}{"cv_id": cvID, "name": parts[1], "is_obsolete": 0}
+			if _, err := db.Lookup("cvterm", where); err == nil {
+				return where, nil
+			}
+		}
+		dbxrefID, err := db.Resolve("dbxref", key)
+		if err != nil {
+			// the bundled fixtures keep the whole identifier as accession
+			dbxrefID, err = db.Lookup("dbxref", map[string]interface{}{"accession": key})
+			if err != nil {
+				return nil, fmt.Errorf("unable to resolve cvterm %s", key)
+			}
+		}
+		return map[string]interface{}{"dbxref_id": dbxrefID}, nil
+	case "dbxref":
+		parts := strings.SplitN(key, ":", 2)
+		if len(parts) != 2 {
+			return nil, fmt.Errorf("dbxref %s is not in DB:accession format", key)
+		}
+		dbID, err := db.Resolve("db", parts[0])
+		if err != nil {
+			return nil, err
+		}
+		return map[string]interface{}{"db_id": dbID, "accession": parts[1]}, nil
+	case "organism":
+		parts := strings.SplitN(key, " ", 2)
+		if len(parts) == 2 {
+			return map[string]interface{}{"genus": parts[0], "species": parts[1]}, nil
+		}
+		return map[string]interface{}{"common_name": key}, nil
+	}
+	cols, err := db.Columns(table)
+	if err != nil {
+		return nil, err
+	}
+	switch {
+	case cols["uniquename"]:
+		return map[string]interface{}{"uniquename": key}, nil
+	case cols["name"]:
+		return map[string]interface{}{"name": key}, nil
+	}
+	return nil, fmt.Errorf("table %s has no natural key", table)
+}
+
+// Returns a copy of the row where references in foreign key columns are
+// resolved to primary keys. A reference could also be given under the column
+// name without the _id suffix, for example type instead of type_id.
+func (db *DB) Row(table string, row map[string]interface{}) (map[string]interface{}, error) {
+	cols, err := db.Columns(table)
+	if err != nil {
+		return nil, err
+	}
+	fks, err := db.ForeignKeys(table)
+	if err != nil {
+		return nil, err
+	}
+	resolved := make(map[string]interface{})
+	for _, k := range sortedKeys(row) {
+		v := row[k]
+		col := k
+		if !cols[col] && cols[col+"_id"] {
+			col = col + "_id"
+		}
+		if !cols[col] {
+			return nil, fmt.Errorf("table %s has no column %s", table, k)
+		}
+		if n, ok := v.(json.Number); ok {
+			v = n.String()
+			if i, err := n.Int64(); err == nil {
+				v = i
+			}
+		}
+		if ref, ok := fks[col]; ok && v != nil {
+			id, err := db.Resolve(ref, v)
+			if err != nil {
+				return nil, err
+			}
+			v = id
+		}
+		resolved[col] = v
+	}
+	return resolved, nil
+}
+
+func sortedKeys(m map[string]interface{}) []string {
+	var keys []string
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func toInt(v interface{}) (int64, bool) {
+	switch n := v.(type) {
+	case int:
+		return int64(n), true
+	case int32:
+		return int64(n), true
+	case int64:
+		return n, true
+	case uint64:
+		return int64(n), true
+	case float64:
+		if n == float64(int64(n)) {
+			return int64(n), true
+		}
+	case json.Number:
+		if i, err := n.Int64(); err == nil {
+			return i, true
+		}
+	}
+	return 0, false
+}
+
+func toString(v interface{}) string {
+	switch s := v.(type) {
+	case []byte:
+		return string(s)
+	case string:
+		return s
+	case nil:
+		return ""
+	}
+	return fmt.Sprint(v)
+}
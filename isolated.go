@@ -0,0 +1,379 @@
+package testchado
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"io"
+	"sync"
+	"testing"
+
+	"github.com/jinzhu/gorm"
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+)
+
+// Name of the database/sql driver that routes every connection through the
+// transaction of an isolated manager
+const txDriverName = "testchado-tx"
+
+// Returned when an isolated manager is asked to deploy the schema, the schema
+// always comes from the manager it was created from
+var ErrIsolated = errors.New("schema of an isolated manager is managed by its parent")
+
+func init() {
+	sql.Register(txDriverName, txDriver{})
+}
+
+// The transactions of active isolated managers, keyed by the data source
+// name given to the testchado-tx driver
+var txSessions = struct {
+	sync.Mutex
+	count    int
+	sessions map[string]*txSession
+}{sessions: make(map[string]*txSession)}
+
+// A database transaction shared by all connections of an isolated manager
+type txSession struct {
+	tx         *sql.Tx
+	mu         sync.Mutex
+	savepoints int
+}
+
+func (sess *txSession) savepoint() string {
+	sess.mu.Lock()
+	defer sess.mu.Unlock()
+	sess.savepoints++
+	return fmt.Sprintf("testchado_sp%d", sess.savepoints)
+}
+
+type txDriver struct{}
+
+func (txDriver) Open(name string) (driver.Conn, error) {
+	txSessions.Lock()
+	defer txSessions.Unlock()
+	sess, ok := txSessions.sessions[name]
+	if !ok {
+		return nil, fmt.Errorf("no active isolated transaction %s", name)
+	}
+	return &txConn{sess}, nil
+}
+
+// A connection that runs everything in the shared transaction. Any transaction
+// started by the application is emulated with a savepoint.
+type txConn struct {
+	sess *txSession
+}
+
+func (c *txConn) Prepare(query string) (driver.Stmt, error) {
+	return &txStmt{sess: c.sess, query: query}, nil
+}
+
+func (c *txConn) Close() error {
+	return nil
+}
+
+func (c *txConn) Begin() (driver.Tx, error) {
+	name := c.sess.savepoint()
+	if _, err := c.sess.tx.Exec("SAVEPOINT " + name); err != nil {
+		return nil, err
+	}
+	return &txSavepoint{sess: c.sess, name: name}, nil
+}
+
+func (c *txConn) ExecContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Result, error) {
+	return c.sess.tx.ExecContext(ctx, query, namedArgs(args)...)
+}
+
+func (c *txConn) QueryContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
+	rows, err := c.sess.tx.QueryContext(ctx, query, namedArgs(args)...)
+	if err != nil {
+		return nil, err
+	}
+	return &txRows{rows}, nil
+}
+
+type txStmt struct {
+	sess  *txSession
+	query string
+}
+
+func (s *txStmt) Close() error {
+	return nil
+}
+
+func (s *txStmt) NumInput() int {
+	return -1
+}
+
+func (s *txStmt) Exec(args []driver.Value) (driver.Result, error) {
+	return s.sess.tx.Exec(s.query, valueArgs(args)...)
+}
+
+func (s *txStmt) Query(args []driver.Value) (driver.Rows, error) {
+	rows, err := s.sess.tx.Query(s.query, valueArgs(args)...)
+	if err != nil {
+		return nil, err
+	}
+	return &txRows{rows}, nil
+}
+
+type txSavepoint struct {
+	sess *txSession
+	name string
+}
+
+func (sp *txSavepoint) Commit() error {
+	_, err := sp.sess.tx.Exec("RELEASE SAVEPOINT " + sp.name)
+	return err
+}
+
+func (sp *txSavepoint) Rollback() error {
+	if _, err := sp.sess.tx.Exec("ROLLBACK TO SAVEPOINT " + sp.name); err != nil {
+		return err
+	}
+	_, err := sp.sess.tx.Exec("RELEASE SAVEPOINT " + sp.name)
+	return err
+}
+
+// Exposes the rows of the shared transaction to database/sql
+type txRows struct {
+	rows *sql.Rows
+}
+
+func (r *txRows) Columns() []string {
+	cols, _ := r.rows.Columns()
+	return cols
+}
+
+func (r *txRows) Close() error {
+	return r.rows.Close()
+}
+
+func (r *txRows) Next(dest []driver.Value) error {
+	if !r.rows.Next() {
+		if err := r.rows.Err(); err != nil {
+			return err
+		}
+		return io.EOF
+	}
+	values := make([]interface{}, len(dest))
+	ptrs := make([]interface{}, len(dest))
+	for i := range values {
+		ptrs[i] = &values[i]
+	}
+	if err := r.rows.Scan(ptrs...); err != nil {
+		return err
+	}
+	for i, v := range values {
+		dest[i] = v
+	}
+	return nil
+}
+
+func namedArgs(args []driver.NamedValue) []interface{} {
+	values := make([]interface{}, len(args))
+	for i, a := range args {
+		if len(a.Name) > 0 {
+			values[i] = sql.Named(a.Name, a.Value)
+			continue
+		}
+		values[i] = a.Value
+	}
+	return values
+}
+
+func valueArgs(args []driver.Value) []interface{} {
+	values := make([]interface{}, len(args))
+	for i, a := range args {
+		values[i] = a
+	}
+	return values
+}
+
+// Name of the savepoint that marks the pristine state of an isolated manager
+const isolatedSavepoint = "testchado_isolated"
+
+// Isolated is a DBManager bound to a single database transaction of the manager
+// it was created from. Its DBHandle and GormHandle run every statement, including
+// the transactions started by the code under test, inside that transaction. All
+// changes are discarded by Rollback, so the schema and fixtures of the parent
+// could be deployed once and shared by many tests.
+type Isolated struct {
+	*DBHelper
+	parent    DBManager
+	name      string
+	sess      *txSession
+	snapshots []isolatedSnapshot
+}
+
+// A savepoint taken by Snapshot
+type isolatedSnapshot struct {
+	name    string
+	presets map[string]bool
+}
+
+// Returns an isolated manager on top of a manager with a deployed chado schema
+func NewIsolated(dbm DBManager) (*Isolated, error) {
+	h, ok := dbm.(interface{ helper() *DBHelper })
+	if !ok {
+		return nil, fmt.Errorf("isolation is not supported for %T", dbm)
+	}
+	parent := h.helper()
+	if !parent.hasLoadedSchema {
+		return nil, ErrSchemaNotLoaded
+	}
+	tx, err := parent.dbhandler.Begin()
+	if err != nil {
+		return nil, err
+	}
+	if postgres, ok := dbm.(*Postgres); ok {
+		if _, err := tx.Exec("SET LOCAL search_path TO " + postgres.Schema); err != nil {
+			tx.Rollback()
+			return nil, err
+		}
+	}
+	if _, err := tx.Exec("SAVEPOINT " + isolatedSavepoint); err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+	sess := &txSession{tx: tx}
+	txSessions.Lock()
+	txSessions.count++
+	name := fmt.Sprintf("tx%d", txSessions.count)
+	txSessions.sessions[name] = sess
+	txSessions.Unlock()
+
+	iso := &Isolated{parent: dbm, name: name, sess: sess}
+	db, err := sql.Open(txDriverName, name)
+	if err != nil {
+		iso.Rollback()
+		return nil, err
+	}
+	gm, err := gorm.Open(parent.driver, txDriverName, name)
+	if err != nil {
+		db.Close()
+		iso.Rollback()
+		return nil, err
+	}
+	gm.SingularTable(true)
+	iso.DBHelper = &DBHelper{
+		driver:          parent.driver,
+		dbsource:        parent.dbsource,
+		dbhandler:       sqlx.NewDb(db, parent.driver),
+		hasLoadedSchema: true,
+		gormHandler:     &gm,
+		loadedPresets:   copyPresets(parent.loadedPresets),
+		isolated:        true,
+	}
+	seed, err := newSeed()
+	if err != nil {
+		iso.Rollback()
+		return nil, err
+	}
+	iso.reseed(seed)
+	return iso, nil
+}
+
+// Isolate returns an isolated manager that is rolled back when the test and
+// all its subtests complete. It stops the test if the isolation could not be setup.
+func Isolate(t testing.TB, dbm DBManager) *Isolated {
+	t.Helper()
+	iso, err := NewIsolated(dbm)
+	if err != nil {
+		t.Fatalf("unable to isolate chado database: %s", err)
+	}
+	if seed, err := testSeed(t.Name()); err == nil {
+		iso.reseed(seed)
+	}
+	t.Cleanup(func() {
+		if err := iso.Rollback(); err != nil {
+			t.Errorf("unable to rollback isolated chado database: %s", err)
+		}
+	})
+	return iso
+}
+
+// Discards all the changes and releases the transaction. The manager could
+// not be used afterwards.
+func (iso *Isolated) Rollback() error {
+	txSessions.Lock()
+	_, active := txSessions.sessions[iso.name]
+	delete(txSessions.sessions, iso.name)
+	txSessions.Unlock()
+	if !active {
+		return nil
+	}
+	if iso.DBHelper != nil {
+		iso.dbhandler.Close()
+		iso.gormHandler.DB().Close()
+		iso.hasLoadedSchema = false
+	}
+	return iso.sess.tx.Rollback()
+}
+
+func (iso *Isolated) Database() string {
+	return iso.parent.Database()
+}
+
+// Same as Rollback
+func (iso *Isolated) DropSchema() error {
+	return iso.Rollback()
+}
+
+// Always returns ErrIsolated, the schema is deployed by the parent manager
+func (iso *Isolated) DeploySchema() error {
+	return ErrIsolated
+}
+
+// Discards all changes made since the manager was created
+func (iso *Isolated) ResetSchema() error {
+	if !iso.hasLoadedSchema {
+		return ErrSchemaNotLoaded
+	}
+	if _, err := iso.sess.tx.Exec("ROLLBACK TO SAVEPOINT " + isolatedSavepoint); err != nil {
+		return err
+	}
+	iso.loadedPresets = make(map[string]bool)
+	if h, ok := iso.parent.(interface{ helper() *DBHelper }); ok {
+		iso.loadedPresets = copyPresets(h.helper().loadedPresets)
+	}
+	iso.snapshots = nil
+	return nil
+}
+
+// Marks the current state with a savepoint of the isolated transaction
+func (iso *Isolated) Snapshot(name string) error {
+	if !iso.hasLoadedSchema {
+		return ErrSchemaNotLoaded
+	}
+	if _, err := iso.sess.tx.Exec("SAVEPOINT " + snapshotSavepoint(name)); err != nil {
+		return err
+	}
+	iso.snapshots = append(iso.snapshots, isolatedSnapshot{name: name, presets: copyPresets(iso.loadedPresets)})
+	return nil
+}
+
+// Rolls back to the savepoint taken by Snapshot. Any snapshot taken
+// afterwards is discarded.
+func (iso *Isolated) Restore(name string) error {
+	for i := len(iso.snapshots) - 1; i >= 0; i-- {
+		snap := iso.snapshots[i]
+		if snap.name != name {
+			continue
+		}
+		if _, err := iso.sess.tx.Exec("ROLLBACK TO SAVEPOINT " + snapshotSavepoint(name)); err != nil {
+			return err
+		}
+		iso.snapshots = iso.snapshots[:i+1]
+		iso.loadedPresets = copyPresets(snap.presets)
+		return nil
+	}
+	return ErrUnknownSnapshot
+}
+
+func snapshotSavepoint(name string) string {
+	return pq.QuoteIdentifier("testchado_snap_" + name)
+}
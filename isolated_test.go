@@ -0,0 +1,113 @@
+package testchado
+
+import (
+    "testing"
+)
+
+func TestIsolated(t *testing.T) {
+    dbm := NewSQLiteManager()
+    if _, err := NewIsolated(dbm); err != ErrSchemaNotLoaded {
+        t.Errorf("should not isolate without schema: %s", err)
+    }
+    _ = dbm.DeploySchema()
+    _ = dbm.LoadDefaultFixture()
+
+    type entries struct{ Counter int }
+    count := func(dbm DBManager) int {
+        e := entries{}
+        if err := dbm.DBHandle().Get(&e, "SELECT count(*) counter FROM organism"); err != nil {
+            t.Fatalf("should have executed the query %s", err)
+        }
+        return e.Counter
+    }
+
+    t.Run("changes", func(t *testing.T) {
+        iso := Isolate(t, dbm)
+        if iso.DeploySchema() != ErrIsolated {
+            t.Error("should not deploy schema in isolation")
+        }
+        sqlx := iso.DBHandle()
+        sqlx.MustExec("INSERT INTO organism (genus, species) VALUES ($1, $2)", "Dictyostelium", "firmibasis")
+        gm := iso.GormHandle().Exec("INSERT INTO organism (genus, species) VALUES (?, ?)", "Dictyostelium", "lacteum")
+        if gm.Error != nil {
+            t.Errorf("should have inserted through gorm: %s", gm.Error)
+        }
+        // application transactions are nested as savepoints
+        tx := sqlx.MustBegin()
+        tx.MustExec("INSERT INTO organism (genus, species) VALUES ($1, $2)", "Polysphondylium", "pallidum")
+        if err := tx.Rollback(); err != nil {
+            t.Errorf("should have rolled back nested transaction: %s", err)
+        }
+        tx = sqlx.MustBegin()
+        tx.MustExec("INSERT INTO organism (genus, species) VALUES ($1, $2)", "Polysphondylium", "violaceum")
+        if err := tx.Commit(); err != nil {
+            t.Errorf("should have committed nested transaction: %s", err)
+        }
+        if c := count(iso); c != 15 {
+            t.Errorf("should have 15 organisms in isolation, got %d", c)
+        }
+        if err := iso.LoadPresetFixture("default"); err == nil {
+            t.Error("should have failed loading default fixture twice")
+        }
+        if err := iso.ResetSchema(); err != nil {
+            t.Errorf("should have reset the isolated schema: %s", err)
+        }
+        if c := count(iso); c != 12 {
+            t.Errorf("should have 12 organisms after reset, got %d", c)
+        }
+        sqlx.MustExec("INSERT INTO organism (genus, species) VALUES ($1, $2)", "Dictyostelium", "firmibasis")
+    })
+    t.Run("snapshot", func(t *testing.T) {
+        iso := Isolate(t, dbm)
+        iso.DBHandle().MustExec("DELETE FROM organism WHERE common_name = 'dicty'")
+        if err := iso.Snapshot("no_dicty"); err != nil {
+            t.Fatalf("should have taken snapshot: %s", err)
+        }
+        iso.DBHandle().MustExec("DELETE FROM organism")
+        if err := iso.Restore("no_dicty"); err != nil {
+            t.Fatalf("should have restored snapshot: %s", err)
+        }
+        if c := count(iso); c != 11 {
+            t.Errorf("should have 11 organisms after restore, got %d", c)
+        }
+        if err := iso.Restore("unknown"); err != ErrUnknownSnapshot {
+            t.Errorf("should not restore unknown snapshot: %s", err)
+        }
+    })
+    if c := count(dbm); c != 12 {
+        t.Errorf("should have rolled back to 12 organisms, got %d", c)
+    }
+}
+
+func TestIsolatedSharedConnection(t *testing.T) {
+    dbm := NewSQLiteManager()
+    _ = dbm.DeploySchema()
+    _ = dbm.LoadDefaultFixture()
+    type entries struct{ Counter int }
+    count := func(dbm DBManager, table string) int {
+        e := entries{}
+        if err := dbm.DBHandle().Get(&e, "SELECT count(*) counter FROM "+table); err != nil {
+            t.Fatalf("should have executed the query %s", err)
+        }
+        return e.Counter
+    }
+    for i := 0; i < 2; i++ {
+        iso, err := NewIsolated(dbm)
+        if err != nil {
+            t.Fatalf("should have isolated chado database: %s", err)
+        }
+        if c := count(dbm, "organism"); c != 12 {
+            t.Errorf("should have 12 organisms in the parent during isolation, got %d", c)
+        }
+        iso.DBHandle().MustExec("INSERT INTO cv (name) VALUES ($1)", "isolated")
+        if c := count(iso, "organism"); c != 12 {
+            t.Errorf("should have 12 organisms in isolation, got %d", c)
+        }
+        if err := iso.Rollback(); err != nil {
+            t.Errorf("should have rolled back: %s", err)
+        }
+    }
+    if c := count(dbm, "cv WHERE name = 'isolated'"); c != 0 {
+        t.Errorf("should have rolled back the isolated cv, got %d", c)
+    }
+}
@@ -9,10 +9,52 @@ import (
 
 var dbmanager testchado.DBManager
 
+// RegisterDBHandler sets the DBManager used by HaveRows, HaveCount and HaveNameCount.
+// It is shared by all tests of the package, use For in parallel tests.
 func RegisterDBHandler(dbm testchado.DBManager) {
 	dbmanager = dbm
 }
 
+// DBMatchers provides the sql matchers bound to a particular DBManager instead of
+// the one set by RegisterDBHandler, so that parallel tests running against separate
+// databases do not interfere with each other.
+//	m := For(chado)
+//	Expect("SELECT count(*) FROM organism").Should(m.HaveCount(12))
+type DBMatchers struct {
+	dbm testchado.DBManager
+}
+
+// For returns the sql matchers bound to the given DBManager
+func For(dbm testchado.DBManager) *DBMatchers {
+	return &DBMatchers{dbm: dbm}
+}
+
+// HaveRows is the same as package level HaveRows bound to the DBManager
+func (m *DBMatchers) HaveRows(expected interface{}) gomega.OmegaMatcher {
+	return &HaveRowsMatcher{expected: expected, dbm: m.dbm}
+}
+
+// HaveCount is the same as package level HaveCount bound to the DBManager
+func (m *DBMatchers) HaveCount(expected interface{}) gomega.OmegaMatcher {
+	return &HaveCountMatcher{expected: expected, dbm: m.dbm}
+}
+
+// HaveNameCount is the same as package level HaveNameCount bound to the DBManager
+func (m *DBMatchers) HaveNameCount(expected interface{}) gomega.OmegaMatcher {
+	return &HaveNameCountMatcher{expected: expected, dbm: m.dbm}
+}
+
+// Returns the bound DBManager, otherwise the registered one
+func manager(dbm testchado.DBManager) (testchado.DBManager, error) {
+	if dbm != nil {
+		return dbm, nil
+	}
+	if dbmanager == nil {
+		return nil, fmt.Errorf("no DBManager is registered, use RegisterDBHandler or For")
+	}
+	return dbmanager, nil
+}
+
 //HaveRows matches the number of rows returned from arbitary SQL query in chado database
 //  Expect("SELECT * FROM feature").Should(HaveRows(20))
 func HaveRows(expected interface{}) gomega.OmegaMatcher {
@@ -21,6 +63,7 @@ func HaveRows(expected interface{}) gomega.OmegaMatcher {
 
 type HaveRowsMatcher struct {
 	expected interface{}
+	dbm      testchado.DBManager
 }
 
 func (matcher *HaveRowsMatcher) Match(actual interface{}) (success bool, err error) {
@@ -33,7 +76,11 @@ func (matcher *HaveRowsMatcher) Match(actual interface{}) (success bool, err err
 		return false, fmt.Errorf("HaveRows matcher expects a integer value")
 	}
 
-	sqlx := dbmanager.DBHandle()
+	dbm, err := manager(matcher.dbm)
+	if err != nil {
+		return false, err
+	}
+	sqlx := dbm.DBHandle()
 	rows, err := sqlx.Queryx(query)
 	if err != nil {
 		return false, fmt.Errorf("could not execute query: %s", err)
@@ -65,6 +112,7 @@ func HaveCount(expected interface{}) gomega.OmegaMatcher {
 
 type HaveCountMatcher struct {
 	expected interface{}
+	dbm      testchado.DBManager
 }
 
 func (matcher *HaveCountMatcher) Match(actual interface{}) (success bool, err error) {
@@ -77,7 +125,11 @@ func (matcher *HaveCountMatcher) Match(actual interface{}) (success bool, err er
 		return false, fmt.Errorf("HaveCount matcher expects a integer value")
 	}
 
-	sqlx := dbmanager.DBHandle()
+	dbm, err := manager(matcher.dbm)
+	if err != nil {
+		return false, err
+	}
+	sqlx := dbm.DBHandle()
 	row := sqlx.QueryRowx(query)
 	var dbcount int
 	err = row.Scan(&dbcount)
@@ -123,6 +175,7 @@ func HaveNameCount(expected interface{}) gomega.OmegaMatcher {
 
 type HaveNameCountMatcher struct {
 	expected interface{}
+	dbm      testchado.DBManager
 }
 
 func (matcher *HaveNameCountMatcher) Match(actual interface{}) (success bool, err error) {
@@ -152,7 +205,11 @@ func (matcher *HaveNameCountMatcher) Match(actual interface{}) (success bool, er
 		return false, fmt.Errorf("The count key does not have an integer count")
 	}
 
-	sqlx := dbmanager.DBHandle()
+	dbm, err := manager(matcher.dbm)
+	if err != nil {
+		return false, err
+	}
+	sqlx := dbm.DBHandle()
 	row := sqlx.QueryRowx(query, args...)
 	var dbcount int
 	err = row.Scan(&dbcount)
@@ -0,0 +1,230 @@
+package matchers
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/dictybase/testchado"
+	"github.com/onsi/gomega"
+)
+
+// Returns the sql condition that matches the cvterm column of a table to a
+// term given by its name, cv:name, DB:name or DB:accession, along with its
+// bind values
+func termCondition(column, term string) (string, []interface{}) {
+	if !strings.Contains(term, ":") {
+		return fmt.Sprintf("%s IN (SELECT cvterm_id FROM cvterm WHERE name = ?)", column), []interface{}{term}
+	}
+	parts := strings.SplitN(term, ":", 2)
+	cond := fmt.Sprintf(`%s IN (
+        SELECT cvterm.cvterm_id FROM cvterm
+        JOIN cv ON cv.cv_id = cvterm.cv_id
+        JOIN dbxref ON dbxref.dbxref_id = cvterm.dbxref_id
+        JOIN db ON db.db_id = dbxref.db_id
+        WHERE (cvterm.name = ? AND (cv.name = ? OR db.name = ? OR dbxref.accession LIKE ?))
+        OR (db.name = ? AND dbxref.accession = ?)
+        OR dbxref.accession = ?
+        )`, column)
+	return cond, []interface{}{parts[1], parts[0], parts[0], parts[0] + ":%", parts[0], parts[1], term}
+}
+
+// Returns the number of rows of a count query with ? bind variables
+func count(dbm testchado.DBManager, query string, args ...interface{}) (int, error) {
+	e := entries{}
+	sqlx := dbm.DBHandle()
+	if err := sqlx.Get(&e, sqlx.Rebind(query), args...); err != nil {
+		return 0, fmt.Errorf("could not execute query: %s", err)
+	}
+	return e.Counter, nil
+}
+
+// Returns the rows of a query with ? bind variables, every row is given as a
+// single string
+func describe(dbm testchado.DBManager, query string, args ...interface{}) ([]string, error) {
+	var rows []string
+	sqlx := dbm.DBHandle()
+	if err := sqlx.Select(&rows, sqlx.Rebind(query), args...); err != nil {
+		return nil, fmt.Errorf("could not execute query: %s", err)
+	}
+	return rows, nil
+}
+
+// Formats the rows found in the database for a failure message
+func existing(what string, rows []string) string {
+	if len(rows) == 0 {
+		return fmt.Sprintf("\nfound no %s", what)
+	}
+	return fmt.Sprintf("\nfound %s\n\t%s", what, strings.Join(rows, "\n\t"))
+}
+
+// HaveFeatureRelationship matches a feature_relationship between two features
+// given by their uniquenames. The type is the name of a cvterm, cv:name or
+// DB:accession. The failure message lists the relationships of the subject.
+//	Expect(chado).Should(HaveFeatureRelationship("DDB0216437", "part_of", "DDB_G0267178"))
+func HaveFeatureRelationship(subject, typ, object string) gomega.OmegaMatcher {
+	return &HaveFeatureRelationshipMatcher{subject: subject, typ: typ, object: object}
+}
+
+type HaveFeatureRelationshipMatcher struct {
+	subject string
+	typ     string
+	object  string
+	found   []string
+}
+
+func (matcher *HaveFeatureRelationshipMatcher) Match(actual interface{}) (success bool, err error) {
+	dbm, ok := actual.(testchado.DBManager)
+	if !ok {
+		return false, fmt.Errorf("HaveFeatureRelationship matcher expects a testchado.DBManager")
+	}
+	cond, args := termCondition("fr.type_id", matcher.typ)
+	q := `
+        SELECT count(*) counter FROM feature_relationship fr
+        JOIN feature subject ON subject.feature_id = fr.subject_id
+        JOIN feature object ON object.feature_id = fr.object_id
+        WHERE subject.uniquename = ? AND object.uniquename = ? AND ` + cond
+	n, err := count(dbm, q, append([]interface{}{matcher.subject, matcher.object}, args...)...)
+	if err != nil {
+		return false, err
+	}
+	matcher.found, err = describe(dbm, `
+        SELECT subject.uniquename || ' ' || type.name || ' ' || object.uniquename
+        FROM feature_relationship fr
+        JOIN feature subject ON subject.feature_id = fr.subject_id
+        JOIN feature object ON object.feature_id = fr.object_id
+        JOIN cvterm type ON type.cvterm_id = fr.type_id
+        WHERE subject.uniquename = ? OR object.uniquename = ?
+        ORDER BY fr.feature_relationship_id
+        `, matcher.subject, matcher.subject)
+	if err != nil {
+		return false, err
+	}
+	return n > 0, nil
+}
+
+func (matcher *HaveFeatureRelationshipMatcher) FailureMessage(actual interface{}) string {
+	return fmt.Sprintf(
+		"Expected\n\t%s %s %s\nto exist in database%s",
+		matcher.subject, matcher.typ, matcher.object,
+		existing("relationships of "+matcher.subject, matcher.found),
+	)
+}
+
+func (matcher *HaveFeatureRelationshipMatcher) NegatedFailureMessage(actual interface{}) string {
+	return fmt.Sprintf("Expected\n\t%s %s %s\nnot to exist in database", matcher.subject, matcher.typ, matcher.object)
+}
+
+// HaveFeatureLoc matches a featureloc of a feature on a source feature, both
+// given by their uniquenames. The coordinates are interbase as stored in chado,
+// the strand is 1, -1 or 0, which also matches a location without strand. The
+// failure message lists the locations of the feature.
+//	Expect(chado).Should(HaveFeatureLoc("DDB_G0267178", "DDB0232428", 1889, 3287, 1))
+func HaveFeatureLoc(feature, srcfeature string, fmin, fmax, strand int) gomega.OmegaMatcher {
+	return &HaveFeatureLocMatcher{feature: feature, srcfeature: srcfeature, fmin: fmin, fmax: fmax, strand: strand}
+}
+
+type HaveFeatureLocMatcher struct {
+	feature    string
+	srcfeature string
+	fmin       int
+	fmax       int
+	strand     int
+	found      []string
+}
+
+func (matcher *HaveFeatureLocMatcher) Match(actual interface{}) (success bool, err error) {
+	dbm, ok := actual.(testchado.DBManager)
+	if !ok {
+		return false, fmt.Errorf("HaveFeatureLoc matcher expects a testchado.DBManager")
+	}
+	q := `
+        SELECT count(*) counter FROM featureloc fl
+        JOIN feature f ON f.feature_id = fl.feature_id
+        JOIN feature src ON src.feature_id = fl.srcfeature_id
+        WHERE f.uniquename = ? AND src.uniquename = ?
+        AND fl.fmin = ? AND fl.fmax = ? AND COALESCE(fl.strand, 0) = ?
+        `
+	n, err := count(dbm, q, matcher.feature, matcher.srcfeature, matcher.fmin, matcher.fmax, matcher.strand)
+	if err != nil {
+		return false, err
+	}
+	matcher.found, err = describe(dbm, `
+        SELECT COALESCE(src.uniquename, '-') || ':' || COALESCE(CAST(fl.fmin AS TEXT), '-') || '..' ||
+        COALESCE(CAST(fl.fmax AS TEXT), '-') || ' strand ' || COALESCE(CAST(fl.strand AS TEXT), '-') ||
+        ' rank ' || CAST(fl.rank AS TEXT)
+        FROM featureloc fl
+        JOIN feature f ON f.feature_id = fl.feature_id
+        LEFT JOIN feature src ON src.feature_id = fl.srcfeature_id
+        WHERE f.uniquename = ?
+        ORDER BY fl.locgroup, fl.rank
+        `, matcher.feature)
+	if err != nil {
+		return false, err
+	}
+	return n > 0, nil
+}
+
+func (matcher *HaveFeatureLocMatcher) location() string {
+	return fmt.Sprintf("%s on %s:%d..%d strand %d", matcher.feature, matcher.srcfeature, matcher.fmin, matcher.fmax, matcher.strand)
+}
+
+func (matcher *HaveFeatureLocMatcher) FailureMessage(actual interface{}) string {
+	return fmt.Sprintf(
+		"Expected\n\t%s\nto exist in database%s",
+		matcher.location(), existing("locations of "+matcher.feature, matcher.found),
+	)
+}
+
+func (matcher *HaveFeatureLocMatcher) NegatedFailureMessage(actual interface{}) string {
+	return fmt.Sprintf("Expected\n\t%s\nnot to exist in database", matcher.location())
+}
+
+// HaveFeatureOfType matches a feature by its uniquename and type, which is the
+// name of a cvterm, cv:name, DB:name or DB:accession. The failure message lists
+// the types of the features with the uniquename.
+//	Expect(chado).Should(HaveFeatureOfType("DDB_G0267178", "SO:gene"))
+//	Expect(chado).Should(HaveFeatureOfType("DDB0216437", "SO:0000234"))
+func HaveFeatureOfType(uniquename, typ string) gomega.OmegaMatcher {
+	return &HaveFeatureOfTypeMatcher{uniquename: uniquename, typ: typ}
+}
+
+type HaveFeatureOfTypeMatcher struct {
+	uniquename string
+	typ        string
+	found      []string
+}
+
+func (matcher *HaveFeatureOfTypeMatcher) Match(actual interface{}) (success bool, err error) {
+	dbm, ok := actual.(testchado.DBManager)
+	if !ok {
+		return false, fmt.Errorf("HaveFeatureOfType matcher expects a testchado.DBManager")
+	}
+	cond, args := termCondition("type_id", matcher.typ)
+	q := "SELECT count(*) counter FROM feature WHERE uniquename = ? AND " + cond
+	n, err := count(dbm, q, append([]interface{}{matcher.uniquename}, args...)...)
+	if err != nil {
+		return false, err
+	}
+	matcher.found, err = describe(dbm, `
+        SELECT cv.name || ':' || type.name FROM feature
+        JOIN cvterm type ON type.cvterm_id = feature.type_id
+        JOIN cv ON cv.cv_id = type.cv_id
+        WHERE feature.uniquename = ?
+        ORDER BY feature.feature_id
+        `, matcher.uniquename)
+	if err != nil {
+		return false, err
+	}
+	return n > 0, nil
+}
+
+func (matcher *HaveFeatureOfTypeMatcher) FailureMessage(actual interface{}) string {
+	return fmt.Sprintf(
+		"Expected\n\tfeature %s of type %s\nto exist in database%s",
+		matcher.uniquename, matcher.typ, existing("types of "+matcher.uniquename, matcher.found),
+	)
+}
+
+func (matcher *HaveFeatureOfTypeMatcher) NegatedFailureMessage(actual interface{}) string {
+	return fmt.Sprintf("Expected\n\tfeature %s of type %s\nnot to exist in database", matcher.uniquename, matcher.typ)
+}
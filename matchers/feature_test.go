@@ -0,0 +1,73 @@
+package matchers
+
+import (
+    "os"
+    "strings"
+    "testing"
+
+    "github.com/dictybase/testchado"
+    . "github.com/onsi/gomega"
+)
+
+func loadSad(t *testing.T) testchado.Chado {
+    chado := testchado.NewTestChado(t)
+    chado.LoadDefaultFixture()
+    fh, err := os.Open("../testdata/sad.gff3")
+    if err != nil {
+        t.Fatal(err)
+    }
+    defer fh.Close()
+    if err := chado.LoadGFF3(fh, "Dictyostelium discoideum"); err != nil {
+        t.Fatalf("should have loaded gff3 file: %s", err)
+    }
+    return chado
+}
+
+func TestFeatureMatchers(t *testing.T) {
+    g := NewGomegaWithT(t)
+    chado := loadSad(t)
+
+    g.Expect(chado).Should(HaveFeatureRelationship("DDB0216437", "part_of", "DDB_G0267178"))
+    g.Expect(chado).Should(HaveFeatureRelationship("DDB0216437", "relationship:part_of", "DDB_G0267178"))
+    g.Expect(chado).ShouldNot(HaveFeatureRelationship("DDB_G0267178", "part_of", "DDB0216437"))
+
+    g.Expect(chado).Should(HaveFeatureLoc("DDB_G0267178", "DDB0232428", 1889, 3287, 1))
+    g.Expect(chado).ShouldNot(HaveFeatureLoc("DDB_G0267178", "DDB0232428", 1890, 3287, 1))
+
+    unstranded := "##gff-version 3\nDDB0232428\tdictyBase\tgene\t4001\t4500\t.\t.\t.\tID=DDB_G0267180\n"
+    if err := chado.LoadGFF3(strings.NewReader(unstranded), "Dictyostelium discoideum"); err != nil {
+        t.Fatalf("should have loaded unstranded feature: %s", err)
+    }
+    g.Expect(chado).Should(HaveFeatureLoc("DDB_G0267180", "DDB0232428", 4000, 4500, 0))
+    g.Expect(chado).ShouldNot(HaveFeatureLoc("DDB_G0267180", "DDB0232428", 4000, 4500, 1))
+
+    g.Expect(chado).Should(HaveFeatureOfType("DDB_G0267178", "SO:gene"))
+    g.Expect(chado).Should(HaveFeatureOfType("DDB_G0267178", "sequence:gene"))
+    g.Expect(chado).Should(HaveFeatureOfType("DDB0216437", "SO:0000234"))
+    g.Expect(chado).ShouldNot(HaveFeatureOfType("DDB_G0267178", "SO:mRNA"))
+}
+
+func TestFeatureMatchersFailureMessage(t *testing.T) {
+    chado := loadSad(t)
+
+    m := HaveFeatureRelationship("DDB0216437", "derives_from", "DDB_G0267178")
+    if ok, err := m.Match(chado); ok || err != nil {
+        t.Fatalf("should not have matched %v", err)
+    }
+    msg := m.FailureMessage(chado)
+    if !strings.Contains(msg, "DDB0216437 part_of DDB_G0267178") || !strings.Contains(msg, "DDB0216437_p derives_from DDB0216437") {
+        t.Errorf("should have listed the relationships, got %s", msg)
+    }
+
+    m = HaveFeatureLoc("DDB_G0267178", "DDB0232428", 1, 3287, 1)
+    m.Match(chado)
+    if msg := m.FailureMessage(chado); !strings.Contains(msg, "DDB0232428:1889..3287 strand 1") {
+        t.Errorf("should have listed the locations, got %s", msg)
+    }
+
+    m = HaveFeatureOfType("DDB_G0267178", "SO:mRNA")
+    m.Match(chado)
+    if msg := m.FailureMessage(chado); !strings.Contains(msg, "sequence:gene") {
+        t.Errorf("should have listed the types, got %s", msg)
+    }
+}
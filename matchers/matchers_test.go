@@ -56,3 +56,30 @@ func TestDatabaseMatchers(t *testing.T) {
     m["count"] = 286
     Expect(query).Should(HaveNameCount(m))
 }
+
+func TestParallelDatabaseMatchers(t *testing.T) {
+    for _, preset := range []string{"default", "cvprop"} {
+        preset := preset
+        t.Run(preset, func(t *testing.T) {
+            t.Parallel()
+            g := NewGomegaWithT(t)
+            chado := testchado.NewTestChado(t)
+            chado.LoadPresetFixture(preset)
+            m := For(chado)
+
+            q := "SELECT count(*) FROM organism"
+            if preset == "default" {
+                g.Expect(q).Should(m.HaveCount(12))
+                g.Expect("SELECT * FROM cvterm").ShouldNot(m.HaveRows(13))
+                return
+            }
+            g.Expect(q).Should(m.HaveCount(0))
+            g.Expect("SELECT * FROM cvterm").Should(m.HaveRows(13))
+            mp := map[string]interface{}{
+                "params": []interface{}{"cv_property"},
+                "count":  13,
+            }
+            g.Expect("SELECT count(*) FROM cvterm JOIN cv ON cv.cv_id = cvterm.cv_id WHERE cv.name = $1").Should(m.HaveNameCount(mp))
+        })
+    }
+}
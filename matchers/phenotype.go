@@ -0,0 +1,154 @@
+package matchers
+
+import (
+	"fmt"
+
+	"github.com/dictybase/testchado"
+	"github.com/onsi/gomega"
+)
+
+// HavePhenotype matches a phenotype by its uniquename or name
+//	Expect(chado).Should(HavePhenotype("delayed aggregation"))
+func HavePhenotype(phenotype string) gomega.OmegaMatcher {
+	return &HavePhenotypeMatcher{phenotype: phenotype}
+}
+
+type HavePhenotypeMatcher struct {
+	phenotype string
+}
+
+func (matcher *HavePhenotypeMatcher) Match(actual interface{}) (success bool, err error) {
+	dbm, ok := actual.(testchado.DBManager)
+	if !ok {
+		return false, fmt.Errorf("HavePhenotype matcher expects a testchado.DBManager")
+	}
+	n, err := count(
+		dbm, "SELECT count(*) counter FROM phenotype WHERE uniquename = ? OR name = ?",
+		matcher.phenotype, matcher.phenotype,
+	)
+	if err != nil {
+		return false, err
+	}
+	return n > 0, nil
+}
+
+func (matcher *HavePhenotypeMatcher) FailureMessage(actual interface{}) string {
+	return fmt.Sprintf("Expected\n\tphenotype %s\nto exist in database", matcher.phenotype)
+}
+
+func (matcher *HavePhenotypeMatcher) NegatedFailureMessage(actual interface{}) string {
+	return fmt.Sprintf("Expected\n\tphenotype %s\nnot to exist in database", matcher.phenotype)
+}
+
+// HaveGenotype matches a genotype by its uniquename or name
+//	Expect(chado).Should(HaveGenotype("axeA-/axeB-"))
+func HaveGenotype(genotype string) gomega.OmegaMatcher {
+	return &HaveGenotypeMatcher{genotype: genotype}
+}
+
+type HaveGenotypeMatcher struct {
+	genotype string
+}
+
+func (matcher *HaveGenotypeMatcher) Match(actual interface{}) (success bool, err error) {
+	dbm, ok := actual.(testchado.DBManager)
+	if !ok {
+		return false, fmt.Errorf("HaveGenotype matcher expects a testchado.DBManager")
+	}
+	n, err := count(
+		dbm, "SELECT count(*) counter FROM genotype WHERE uniquename = ? OR name = ?",
+		matcher.genotype, matcher.genotype,
+	)
+	if err != nil {
+		return false, err
+	}
+	return n > 0, nil
+}
+
+func (matcher *HaveGenotypeMatcher) FailureMessage(actual interface{}) string {
+	return fmt.Sprintf("Expected\n\tgenotype %s\nto exist in database", matcher.genotype)
+}
+
+func (matcher *HaveGenotypeMatcher) NegatedFailureMessage(actual interface{}) string {
+	return fmt.Sprintf("Expected\n\tgenotype %s\nnot to exist in database", matcher.genotype)
+}
+
+// HavePhenstatement matches a phenstatement of a genotype and a phenotype, both
+// given by their uniquename or name, in an environment given by its uniquename
+// and according to a publication given by its uniquename or DB:accession. An
+// empty environment or pub matches any. The failure message lists the
+// phenstatements of the genotype.
+//	Expect(chado).Should(HavePhenstatement("axeA-/axeB-", "delayed aggregation", "starvation", "PMID:4312"))
+//	Expect(chado).Should(HavePhenstatement("axeA-/axeB-", "delayed aggregation", "", ""))
+func HavePhenstatement(genotype, phenotype, environment, pub string) gomega.OmegaMatcher {
+	return &HavePhenstatementMatcher{genotype: genotype, phenotype: phenotype, environment: environment, pub: pub}
+}
+
+type HavePhenstatementMatcher struct {
+	genotype    string
+	phenotype   string
+	environment string
+	pub         string
+	found       []string
+}
+
+func (matcher *HavePhenstatementMatcher) Match(actual interface{}) (success bool, err error) {
+	dbm, ok := actual.(testchado.DBManager)
+	if !ok {
+		return false, fmt.Errorf("HavePhenstatement matcher expects a testchado.DBManager")
+	}
+	from := `
+        FROM phenstatement ps
+        JOIN genotype g ON g.genotype_id = ps.genotype_id
+        JOIN phenotype p ON p.phenotype_id = ps.phenotype_id
+        JOIN environment e ON e.environment_id = ps.environment_id
+        JOIN pub ON pub.pub_id = ps.pub_id
+        WHERE (g.uniquename = ? OR g.name = ?)
+        `
+	q := "SELECT count(*) counter " + from + " AND (p.uniquename = ? OR p.name = ?)"
+	args := []interface{}{matcher.genotype, matcher.genotype, matcher.phenotype, matcher.phenotype}
+	if len(matcher.environment) > 0 {
+		q += " AND e.uniquename = ?"
+		args = append(args, matcher.environment)
+	}
+	if len(matcher.pub) > 0 {
+		cond, pubArgs := pubCondition("ps.pub_id", matcher.pub)
+		q += " AND " + cond
+		args = append(args, pubArgs...)
+	}
+	n, err := count(dbm, q, args...)
+	if err != nil {
+		return false, err
+	}
+	matcher.found, err = describe(
+		dbm, "SELECT COALESCE(p.name, p.uniquename) || ' in ' || e.uniquename || ' from ' || pub.uniquename "+from+
+			" ORDER BY ps.phenstatement_id",
+		matcher.genotype, matcher.genotype,
+	)
+	if err != nil {
+		return false, err
+	}
+	return n > 0, nil
+}
+
+func (matcher *HavePhenstatementMatcher) statement() string {
+	s := fmt.Sprintf("%s with phenotype %s", matcher.genotype, matcher.phenotype)
+	if len(matcher.environment) > 0 {
+		s += " in " + matcher.environment
+	}
+	if len(matcher.pub) > 0 {
+		s += " from " + matcher.pub
+	}
+	return s
+}
+
+func (matcher *HavePhenstatementMatcher) FailureMessage(actual interface{}) string {
+	return fmt.Sprintf(
+		"Expected\n\t%s\nto exist in database%s",
+		matcher.statement(), existing("phenotypes of "+matcher.genotype, matcher.found),
+	)
+}
+
+func (matcher *HavePhenstatementMatcher) NegatedFailureMessage(actual interface{}) string {
+	return fmt.Sprintf("Expected\n\t%s\nnot to exist in database", matcher.statement())
+}
@@ -0,0 +1,51 @@
+package matchers
+
+import (
+    "strings"
+    "testing"
+
+    "github.com/dictybase/testchado"
+    "github.com/dictybase/testchado/builder"
+    . "github.com/onsi/gomega"
+)
+
+func TestPhenotypeMatchers(t *testing.T) {
+    g := NewGomegaWithT(t)
+    chado := testchado.NewTestChado(t)
+    _ = chado.LoadDefaultFixture()
+    if _, err := builder.Genotype("DSC_G0001").Name("axeA-/axeB-").Create(chado); err != nil {
+        t.Fatal(err)
+    }
+    _, err := builder.Phenotype("DDB_PHEN_1").
+        Name("delayed aggregation").
+        Observable("aggregation").
+        Attr("delayed").
+        Create(chado)
+    if err != nil {
+        t.Fatal(err)
+    }
+    _, err = builder.Phenstatement("DSC_G0001", "DDB_PHEN_1").
+        Environment("starvation").
+        Pub("PMID:4312").
+        Create(chado)
+    if err != nil {
+        t.Fatal(err)
+    }
+
+    g.Expect(chado).Should(HavePhenotype("DDB_PHEN_1"))
+    g.Expect(chado).Should(HavePhenotype("delayed aggregation"))
+    g.Expect(chado).ShouldNot(HavePhenotype("aberrant spores"))
+    g.Expect(chado).Should(HaveGenotype("DSC_G0001"))
+    g.Expect(chado).Should(HaveGenotype("axeA-/axeB-"))
+    g.Expect(chado).ShouldNot(HaveGenotype("axeC-"))
+    g.Expect(chado).Should(HavePhenstatement("axeA-/axeB-", "delayed aggregation", "starvation", "PMID:4312"))
+    g.Expect(chado).Should(HavePhenstatement("DSC_G0001", "DDB_PHEN_1", "", ""))
+    g.Expect(chado).ShouldNot(HavePhenstatement("DSC_G0001", "DDB_PHEN_1", "unspecified", ""))
+    g.Expect(chado).ShouldNot(HavePhenstatement("DSC_G0001", "DDB_PHEN_1", "", "PMID:1"))
+
+    m := HavePhenstatement("DSC_G0001", "aberrant spores", "", "")
+    m.Match(chado)
+    if msg := m.FailureMessage(chado); !strings.Contains(msg, "delayed aggregation in starvation from PMID:4312") {
+        t.Errorf("should have listed the phenotypes of the genotype, got %s", msg)
+    }
+}
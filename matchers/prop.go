@@ -0,0 +1,139 @@
+package matchers
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/dictybase/testchado"
+	"github.com/dictybase/testchado/internal/chadodb"
+	"github.com/onsi/gomega"
+)
+
+// HaveProp matches a property of a row in the prop table of a chado table, for
+// example featureprop of feature. The row is given by its natural key
+//
+//	feature, stock ...  uniquename, otherwise name
+//	organism            genus and species separated by space, otherwise common name
+//	cvterm              name, cv:name or DB:accession
+//	pub                 uniquename or DB:accession of its pub_dbxref
+//
+// and the type of the property by the name of its cvterm or cv:name. The failure
+// message lists the properties of the row.
+//	Expect(chado).Should(HaveProp("feature", "DDB_G0267178", "feature_property:Note", "sad gene"))
+func HaveProp(table, entity, typ, value string) gomega.OmegaMatcher {
+	return &HavePropMatcher{table: strings.TrimSuffix(table, "prop"), entity: entity, typ: typ, value: value}
+}
+
+// HaveFeatureprop matches a featureprop of a feature by its uniquename
+//	Expect(chado).Should(HaveFeatureprop("DDB_G0267178", "Note", "two alleles"))
+func HaveFeatureprop(feature, typ, value string) gomega.OmegaMatcher {
+	return HaveProp("feature", feature, typ, value)
+}
+
+// HaveOrganismprop matches an organismprop of an organism by its genus and
+// species or common name
+//	Expect(chado).Should(HaveOrganismprop("Dictyostelium discoideum", "strain_count", "12"))
+func HaveOrganismprop(organism, typ, value string) gomega.OmegaMatcher {
+	return HaveProp("organism", organism, typ, value)
+}
+
+// HaveStockprop matches a stockprop of a stock by its uniquename
+//	Expect(chado).Should(HaveStockprop("DBS0235412", "mutagenesis_method", "REMI"))
+func HaveStockprop(stock, typ, value string) gomega.OmegaMatcher {
+	return HaveProp("stock", stock, typ, value)
+}
+
+// HavePubprop matches a pubprop of a publication by its uniquename or
+// DB:accession of its pub_dbxref
+//	Expect(chado).Should(HavePubprop("PMID:4312", "abstract", "..."))
+func HavePubprop(pub, typ, value string) gomega.OmegaMatcher {
+	return HaveProp("pub", pub, typ, value)
+}
+
+// HaveCvtermprop matches a cvtermprop of a cvterm by its name, cv:name or
+// DB:accession
+//	Expect(chado).Should(HaveCvtermprop("GO:0005634", "comment", "See also nuclear envelope"))
+func HaveCvtermprop(cvterm, typ, value string) gomega.OmegaMatcher {
+	return HaveProp("cvterm", cvterm, typ, value)
+}
+
+type HavePropMatcher struct {
+	table  string
+	entity string
+	typ    string
+	value  string
+	found  []string
+}
+
+func (matcher *HavePropMatcher) Match(actual interface{}) (success bool, err error) {
+	dbm, ok := actual.(testchado.DBManager)
+	if !ok {
+		return false, fmt.Errorf("HaveProp matcher expects a testchado.DBManager")
+	}
+	owner, args, err := entityCondition(dbm, matcher.table, "o", matcher.entity)
+	if err != nil {
+		return false, err
+	}
+	from := fmt.Sprintf(
+		"FROM %[1]sprop p JOIN %[1]s o ON o.%[1]s_id = p.%[1]s_id JOIN cvterm type ON type.cvterm_id = p.type_id WHERE %s",
+		matcher.table, owner,
+	)
+	cond, typeArgs := termCondition("p.type_id", matcher.typ)
+	q := "SELECT count(*) counter " + from + " AND p.value = ? AND " + cond
+	n, err := count(dbm, q, append(append(append([]interface{}{}, args...), matcher.value), typeArgs...)...)
+	if err != nil {
+		return false, err
+	}
+	matcher.found, err = describe(
+		dbm, "SELECT type.name || ' = ' || COALESCE(p.value, '') "+from+" ORDER BY type.name, p.rank",
+		args...,
+	)
+	if err != nil {
+		return false, err
+	}
+	return n > 0, nil
+}
+
+func (matcher *HavePropMatcher) FailureMessage(actual interface{}) string {
+	return fmt.Sprintf(
+		"Expected\n\t%sprop %s = %s of %s\nto exist in database%s",
+		matcher.table, matcher.typ, matcher.value, matcher.entity,
+		existing(matcher.table+"props of "+matcher.entity, matcher.found),
+	)
+}
+
+func (matcher *HavePropMatcher) NegatedFailureMessage(actual interface{}) string {
+	return fmt.Sprintf(
+		"Expected\n\t%sprop %s = %s of %s\nnot to exist in database",
+		matcher.table, matcher.typ, matcher.value, matcher.entity,
+	)
+}
+
+// Returns the sql condition that matches the rows of a table, aliased as alias,
+// to a natural key
+func entityCondition(dbm testchado.DBManager, table, alias, key string) (string, []interface{}, error) {
+	switch table {
+	case "cvterm":
+		cond, args := termCondition(alias+".cvterm_id", key)
+		return cond, args, nil
+	case "pub":
+		cond, args := pubCondition(alias+".pub_id", key)
+		return cond, args, nil
+	}
+	where, err := chadodb.New(dbm.DBHandle()).NaturalKey(table, key)
+	if err != nil {
+		return "", nil, err
+	}
+	var conds []string
+	var args []interface{}
+	for col := range where {
+		conds = append(conds, col)
+	}
+	sort.Strings(conds)
+	for i, col := range conds {
+		args = append(args, where[col])
+		conds[i] = fmt.Sprintf("%s.%s = ?", alias, col)
+	}
+	return strings.Join(conds, " AND "), args, nil
+}
@@ -0,0 +1,49 @@
+package matchers
+
+import (
+    "os"
+    "path/filepath"
+    "strings"
+    "testing"
+
+    "github.com/dictybase/testchado/builder"
+    . "github.com/onsi/gomega"
+)
+
+func TestPropMatchers(t *testing.T) {
+    g := NewGomegaWithT(t)
+    chado := loadSad(t)
+    if _, err := builder.Cvterm("organism_property", "strain_count").Create(chado); err != nil {
+        t.Fatal(err)
+    }
+    fixture := filepath.Join(t.TempDir(), "props.yaml")
+    content := `
+- organismprop:
+    - organism: Dictyostelium discoideum
+      type: organism_property:strain_count
+      value: "12"
+`
+    if err := os.WriteFile(fixture, []byte(content), 0644); err != nil {
+        t.Fatal(err)
+    }
+    if err := chado.LoadCustomFixture(fixture); err != nil {
+        t.Fatalf("should have loaded fixture: %s", err)
+    }
+
+    g.Expect(chado).Should(HaveFeatureprop("DDB_G0267178", "Note", "two alleles"))
+    g.Expect(chado).Should(HaveProp("featureprop", "DDB_G0267178", "feature_property:Note", "sad gene"))
+    g.Expect(chado).ShouldNot(HaveFeatureprop("DDB_G0267178", "Note", "three alleles"))
+    g.Expect(chado).ShouldNot(HaveFeatureprop("DDB0216437", "Note", "two alleles"))
+    g.Expect(chado).Should(HaveOrganismprop("Dictyostelium discoideum", "strain_count", "12"))
+    g.Expect(chado).Should(HaveOrganismprop("dicty", "organism_property:strain_count", "12"))
+    g.Expect(chado).ShouldNot(HaveStockprop("DBS0235412", "strain_count", "12"))
+
+    m := HaveFeatureprop("DDB_G0267178", "Note", "three alleles")
+    if ok, err := m.Match(chado); ok || err != nil {
+        t.Fatalf("should not have matched %v", err)
+    }
+    msg := m.FailureMessage(chado)
+    if !strings.Contains(msg, "Note = sad gene") || !strings.Contains(msg, "Note = two alleles") {
+        t.Errorf("should have listed the actual values, got %s", msg)
+    }
+}
@@ -0,0 +1,161 @@
+package matchers
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/dictybase/testchado"
+	"github.com/onsi/gomega"
+)
+
+// Returns the sql condition that matches the pub_id column of a table to a
+// publication given by its uniquename or, in DB:accession format, by its
+// pub_dbxref, along with its bind values
+func pubCondition(column, pub string) (string, []interface{}) {
+	cond := fmt.Sprintf("%s IN (SELECT pub_id FROM pub WHERE uniquename = ?", column)
+	args := []interface{}{pub}
+	if strings.Contains(pub, ":") {
+		d := strings.SplitN(pub, ":", 2)
+		cond += `
+        UNION SELECT pub_dbxref.pub_id FROM pub_dbxref
+        JOIN dbxref ON dbxref.dbxref_id = pub_dbxref.dbxref_id
+        JOIN db ON db.db_id = dbxref.db_id
+        WHERE db.name = ? AND dbxref.accession = ?`
+		args = append(args, d[0], d[1])
+	}
+	return cond + ")", args
+}
+
+// HavePub matches a publication by its uniquename. In case of DB:accession
+// format, for example PMID:12345, it also matches the dbxrefs of the
+// publications.
+//	Expect(chado).Should(HavePub("PMID:12345"))
+func HavePub(pub string) gomega.OmegaMatcher {
+	return &HavePubMatcher{pub: pub}
+}
+
+type HavePubMatcher struct {
+	pub string
+}
+
+func (matcher *HavePubMatcher) Match(actual interface{}) (success bool, err error) {
+	dbm, ok := actual.(testchado.DBManager)
+	if !ok {
+		return false, fmt.Errorf("HavePub matcher expects a testchado.DBManager")
+	}
+	cond, args := pubCondition("pub_id", matcher.pub)
+	n, err := count(dbm, "SELECT count(*) counter FROM pub WHERE "+cond, args...)
+	if err != nil {
+		return false, err
+	}
+	return n > 0, nil
+}
+
+func (matcher *HavePubMatcher) FailureMessage(actual interface{}) string {
+	return fmt.Sprintf("Expected\n\tpub %s\nto exist in database", matcher.pub)
+}
+
+func (matcher *HavePubMatcher) NegatedFailureMessage(actual interface{}) string {
+	return fmt.Sprintf("Expected\n\tpub %s\nnot to exist in database", matcher.pub)
+}
+
+// HavePubAuthor matches an author of a publication, given as in HavePub, by
+// the surname and rank. The failure message lists the authors of the
+// publication.
+//	Expect(chado).Should(HavePubAuthor("PMID:12345", "Kay", 0))
+func HavePubAuthor(pub, surname string, rank int) gomega.OmegaMatcher {
+	return &HavePubAuthorMatcher{pub: pub, surname: surname, rank: rank}
+}
+
+type HavePubAuthorMatcher struct {
+	pub     string
+	surname string
+	rank    int
+	found   []string
+}
+
+func (matcher *HavePubAuthorMatcher) Match(actual interface{}) (success bool, err error) {
+	dbm, ok := actual.(testchado.DBManager)
+	if !ok {
+		return false, fmt.Errorf("HavePubAuthor matcher expects a testchado.DBManager")
+	}
+	cond, args := pubCondition("pub_id", matcher.pub)
+	n, err := count(
+		dbm, "SELECT count(*) counter FROM pubauthor WHERE surname = ? AND rank = ? AND "+cond,
+		append([]interface{}{matcher.surname, matcher.rank}, args...)...,
+	)
+	if err != nil {
+		return false, err
+	}
+	matcher.found, err = describe(
+		dbm,
+		"SELECT CAST(rank AS TEXT) || ' ' || surname || COALESCE(', ' || givennames, '') FROM pubauthor WHERE "+cond+" ORDER BY rank",
+		args...,
+	)
+	if err != nil {
+		return false, err
+	}
+	return n > 0, nil
+}
+
+func (matcher *HavePubAuthorMatcher) FailureMessage(actual interface{}) string {
+	return fmt.Sprintf(
+		"Expected\n\tauthor %s of rank %d of pub %s\nto exist in database%s",
+		matcher.surname, matcher.rank, matcher.pub, existing("authors of "+matcher.pub, matcher.found),
+	)
+}
+
+func (matcher *HavePubAuthorMatcher) NegatedFailureMessage(actual interface{}) string {
+	return fmt.Sprintf(
+		"Expected\n\tauthor %s of rank %d of pub %s\nnot to exist in database",
+		matcher.surname, matcher.rank, matcher.pub,
+	)
+}
+
+// HaveFeaturePub matches a feature_pub between a feature, given by its
+// uniquename, and a publication given as in HavePub. The failure message lists
+// the publications of the feature.
+//	Expect(chado).Should(HaveFeaturePub("DDB_G0267178", "PMID:12345"))
+func HaveFeaturePub(feature, pub string) gomega.OmegaMatcher {
+	return &HaveFeaturePubMatcher{feature: feature, pub: pub}
+}
+
+type HaveFeaturePubMatcher struct {
+	feature string
+	pub     string
+	found   []string
+}
+
+func (matcher *HaveFeaturePubMatcher) Match(actual interface{}) (success bool, err error) {
+	dbm, ok := actual.(testchado.DBManager)
+	if !ok {
+		return false, fmt.Errorf("HaveFeaturePub matcher expects a testchado.DBManager")
+	}
+	from := `
+        FROM feature_pub fp
+        JOIN feature ON feature.feature_id = fp.feature_id
+        JOIN pub ON pub.pub_id = fp.pub_id
+        WHERE feature.uniquename = ?
+        `
+	cond, args := pubCondition("fp.pub_id", matcher.pub)
+	n, err := count(dbm, "SELECT count(*) counter "+from+" AND "+cond, append([]interface{}{matcher.feature}, args...)...)
+	if err != nil {
+		return false, err
+	}
+	matcher.found, err = describe(dbm, "SELECT pub.uniquename "+from+" ORDER BY pub.uniquename", matcher.feature)
+	if err != nil {
+		return false, err
+	}
+	return n > 0, nil
+}
+
+func (matcher *HaveFeaturePubMatcher) FailureMessage(actual interface{}) string {
+	return fmt.Sprintf(
+		"Expected\n\tfeature %s with pub %s\nto exist in database%s",
+		matcher.feature, matcher.pub, existing("pubs of "+matcher.feature, matcher.found),
+	)
+}
+
+func (matcher *HaveFeaturePubMatcher) NegatedFailureMessage(actual interface{}) string {
+	return fmt.Sprintf("Expected\n\tfeature %s with pub %s\nnot to exist in database", matcher.feature, matcher.pub)
+}
@@ -0,0 +1,74 @@
+package matchers
+
+import (
+    "os"
+    "path/filepath"
+    "strings"
+    "testing"
+
+    "github.com/dictybase/testchado/builder"
+    . "github.com/onsi/gomega"
+)
+
+func TestPubMatchers(t *testing.T) {
+    g := NewGomegaWithT(t)
+    chado := loadSad(t)
+    if _, err := builder.Cvterm("pub_type", "journal_article").Create(chado); err != nil {
+        t.Fatal(err)
+    }
+    fixture := filepath.Join(t.TempDir(), "pubs.yaml")
+    content := `
+- pub:
+    - uniquename: sadA paper
+      title: Sad gene of Dictyostelium
+      type: pub_type:journal_article
+- dbxref:
+    - db: PMID
+      accession: "12345"
+- pub_dbxref:
+    - pub: sadA paper
+      dbxref: PMID:12345
+- pubauthor:
+    - pub: sadA paper
+      rank: 0
+      surname: Kay
+      givennames: R
+    - pub: sadA paper
+      rank: 1
+      surname: Williams
+- pubprop:
+    - pub: sadA paper
+      type: pub_type:journal_article
+      value: Dev Biol
+- feature_pub:
+    - feature: DDB_G0267178
+      pub: sadA paper
+`
+    if err := os.WriteFile(fixture, []byte(content), 0644); err != nil {
+        t.Fatal(err)
+    }
+    if err := chado.LoadCustomFixture(fixture); err != nil {
+        t.Fatalf("should have loaded fixture: %s", err)
+    }
+
+    g.Expect(chado).Should(HavePub("sadA paper"))
+    g.Expect(chado).Should(HavePub("PMID:12345"))
+    g.Expect(chado).ShouldNot(HavePub("PMID:54321"))
+    g.Expect(chado).Should(HavePubAuthor("PMID:12345", "Kay", 0))
+    g.Expect(chado).Should(HavePubAuthor("sadA paper", "Williams", 1))
+    g.Expect(chado).ShouldNot(HavePubAuthor("PMID:12345", "Kay", 1))
+    g.Expect(chado).Should(HaveFeaturePub("DDB_G0267178", "PMID:12345"))
+    g.Expect(chado).ShouldNot(HaveFeaturePub("DDB0216437", "PMID:12345"))
+    g.Expect(chado).Should(HavePubprop("PMID:12345", "journal_article", "Dev Biol"))
+
+    m := HavePubAuthor("PMID:12345", "Kay", 1)
+    m.Match(chado)
+    if msg := m.FailureMessage(chado); !strings.Contains(msg, "0 Kay, R") || !strings.Contains(msg, "1 Williams") {
+        t.Errorf("should have listed the authors, got %s", msg)
+    }
+    m = HaveFeaturePub("DDB_G0267178", "PMID:54321")
+    m.Match(chado)
+    if msg := m.FailureMessage(chado); !strings.Contains(msg, "sadA paper") {
+        t.Errorf("should have listed the pubs, got %s", msg)
+    }
+}
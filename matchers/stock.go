@@ -0,0 +1,195 @@
+package matchers
+
+import (
+	"fmt"
+
+	"github.com/dictybase/testchado"
+	"github.com/onsi/gomega"
+)
+
+// HaveStock matches uniquename of a stock in chado database.
+//	Expect(chado).Should(HaveStock("DBS0235412"))
+func HaveStock(uniquename string) gomega.OmegaMatcher {
+	return &HaveStockMatcher{uniquename: uniquename}
+}
+
+type HaveStockMatcher struct {
+	uniquename string
+}
+
+func (matcher *HaveStockMatcher) Match(actual interface{}) (success bool, err error) {
+	dbm, ok := actual.(testchado.DBManager)
+	if !ok {
+		return false, fmt.Errorf("HaveStock matcher expects a testchado.DBManager")
+	}
+	n, err := count(dbm, "SELECT count(*) counter FROM stock WHERE uniquename = ?", matcher.uniquename)
+	if err != nil {
+		return false, err
+	}
+	return n > 0, nil
+}
+
+func (matcher *HaveStockMatcher) FailureMessage(actual interface{}) string {
+	return fmt.Sprintf("Expected\n\tstock %s\nto exist in database", matcher.uniquename)
+}
+
+func (matcher *HaveStockMatcher) NegatedFailureMessage(actual interface{}) string {
+	return fmt.Sprintf("Expected\n\tstock %s\nnot to exist in database", matcher.uniquename)
+}
+
+// HaveStockInCollection matches a stock, given by its uniquename, in a
+// stockcollection given by its uniquename or name. The failure message lists
+// the collections of the stock.
+//	Expect(chado).Should(HaveStockInCollection("DBS0235412", "Dicty Stock Center"))
+func HaveStockInCollection(stock, collection string) gomega.OmegaMatcher {
+	return &HaveStockInCollectionMatcher{stock: stock, collection: collection}
+}
+
+type HaveStockInCollectionMatcher struct {
+	stock      string
+	collection string
+	found      []string
+}
+
+func (matcher *HaveStockInCollectionMatcher) Match(actual interface{}) (success bool, err error) {
+	dbm, ok := actual.(testchado.DBManager)
+	if !ok {
+		return false, fmt.Errorf("HaveStockInCollection matcher expects a testchado.DBManager")
+	}
+	from := `
+        FROM stockcollection_stock scs
+        JOIN stock ON stock.stock_id = scs.stock_id
+        JOIN stockcollection sc ON sc.stockcollection_id = scs.stockcollection_id
+        WHERE stock.uniquename = ?
+        `
+	n, err := count(
+		dbm, "SELECT count(*) counter "+from+" AND (sc.uniquename = ? OR sc.name = ?)",
+		matcher.stock, matcher.collection, matcher.collection,
+	)
+	if err != nil {
+		return false, err
+	}
+	matcher.found, err = describe(dbm, "SELECT sc.uniquename "+from+" ORDER BY sc.uniquename", matcher.stock)
+	if err != nil {
+		return false, err
+	}
+	return n > 0, nil
+}
+
+func (matcher *HaveStockInCollectionMatcher) FailureMessage(actual interface{}) string {
+	return fmt.Sprintf(
+		"Expected\n\tstock %s in collection %s\nto exist in database%s",
+		matcher.stock, matcher.collection, existing("collections of "+matcher.stock, matcher.found),
+	)
+}
+
+func (matcher *HaveStockInCollectionMatcher) NegatedFailureMessage(actual interface{}) string {
+	return fmt.Sprintf("Expected\n\tstock %s in collection %s\nnot to exist in database", matcher.stock, matcher.collection)
+}
+
+// HaveStockRelationship matches a stock_relationship between two stocks given
+// by their uniquenames. The type is the name of a cvterm, cv:name or
+// DB:accession. The failure message lists the relationships of the subject.
+//	Expect(chado).Should(HaveStockRelationship("DBS0351471", "derived_from", "DBS0235412"))
+func HaveStockRelationship(subject, typ, object string) gomega.OmegaMatcher {
+	return &HaveStockRelationshipMatcher{subject: subject, typ: typ, object: object}
+}
+
+type HaveStockRelationshipMatcher struct {
+	subject string
+	typ     string
+	object  string
+	found   []string
+}
+
+func (matcher *HaveStockRelationshipMatcher) Match(actual interface{}) (success bool, err error) {
+	dbm, ok := actual.(testchado.DBManager)
+	if !ok {
+		return false, fmt.Errorf("HaveStockRelationship matcher expects a testchado.DBManager")
+	}
+	cond, args := termCondition("sr.type_id", matcher.typ)
+	q := `
+        SELECT count(*) counter FROM stock_relationship sr
+        JOIN stock subject ON subject.stock_id = sr.subject_id
+        JOIN stock object ON object.stock_id = sr.object_id
+        WHERE subject.uniquename = ? AND object.uniquename = ? AND ` + cond
+	n, err := count(dbm, q, append([]interface{}{matcher.subject, matcher.object}, args...)...)
+	if err != nil {
+		return false, err
+	}
+	matcher.found, err = describe(dbm, `
+        SELECT subject.uniquename || ' ' || type.name || ' ' || object.uniquename
+        FROM stock_relationship sr
+        JOIN stock subject ON subject.stock_id = sr.subject_id
+        JOIN stock object ON object.stock_id = sr.object_id
+        JOIN cvterm type ON type.cvterm_id = sr.type_id
+        WHERE subject.uniquename = ? OR object.uniquename = ?
+        ORDER BY sr.stock_relationship_id
+        `, matcher.subject, matcher.subject)
+	if err != nil {
+		return false, err
+	}
+	return n > 0, nil
+}
+
+func (matcher *HaveStockRelationshipMatcher) FailureMessage(actual interface{}) string {
+	return fmt.Sprintf(
+		"Expected\n\t%s %s %s\nto exist in database%s",
+		matcher.subject, matcher.typ, matcher.object,
+		existing("relationships of "+matcher.subject, matcher.found),
+	)
+}
+
+func (matcher *HaveStockRelationshipMatcher) NegatedFailureMessage(actual interface{}) string {
+	return fmt.Sprintf("Expected\n\t%s %s %s\nnot to exist in database", matcher.subject, matcher.typ, matcher.object)
+}
+
+// HaveStockGenotype matches a genotype, given by its uniquename or name, of a
+// stock given by its uniquename. The failure message lists the genotypes of
+// the stock.
+//	Expect(chado).Should(HaveStockGenotype("DBS0235412", "axeA-/axeB-"))
+func HaveStockGenotype(stock, genotype string) gomega.OmegaMatcher {
+	return &HaveStockGenotypeMatcher{stock: stock, genotype: genotype}
+}
+
+type HaveStockGenotypeMatcher struct {
+	stock    string
+	genotype string
+	found    []string
+}
+
+func (matcher *HaveStockGenotypeMatcher) Match(actual interface{}) (success bool, err error) {
+	dbm, ok := actual.(testchado.DBManager)
+	if !ok {
+		return false, fmt.Errorf("HaveStockGenotype matcher expects a testchado.DBManager")
+	}
+	from := `
+        FROM stock_genotype sg
+        JOIN stock ON stock.stock_id = sg.stock_id
+        JOIN genotype ON genotype.genotype_id = sg.genotype_id
+        WHERE stock.uniquename = ?
+        `
+	n, err := count(
+		dbm, "SELECT count(*) counter "+from+" AND (genotype.uniquename = ? OR genotype.name = ?)",
+		matcher.stock, matcher.genotype, matcher.genotype,
+	)
+	if err != nil {
+		return false, err
+	}
+	matcher.found, err = describe(dbm, "SELECT genotype.uniquename "+from+" ORDER BY genotype.uniquename", matcher.stock)
+	if err != nil {
+		return false, err
+	}
+	return n > 0, nil
+}
+
+func (matcher *HaveStockGenotypeMatcher) FailureMessage(actual interface{}) string {
+	return fmt.Sprintf(
+		"Expected\n\tstock %s with genotype %s\nto exist in database%s",
+		matcher.stock, matcher.genotype, existing("genotypes of "+matcher.stock, matcher.found),
+	)
+}
+
+func (matcher *HaveStockGenotypeMatcher) NegatedFailureMessage(actual interface{}) string {
+	return fmt.Sprintf("Expected\n\tstock %s with genotype %s\nnot to exist in database", matcher.stock, matcher.genotype)
+}
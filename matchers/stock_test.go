@@ -0,0 +1,53 @@
+package matchers
+
+import (
+    "strings"
+    "testing"
+
+    "github.com/dictybase/testchado"
+    "github.com/dictybase/testchado/builder"
+    . "github.com/onsi/gomega"
+)
+
+func TestStockMatchers(t *testing.T) {
+    g := NewGomegaWithT(t)
+    chado := testchado.NewTestChado(t)
+    _, err := builder.Stock("DBS0235412").Name("AX4").
+        Type("dicty_stockcenter", "strain").
+        Collection("Dicty Stock Center").
+        Create(chado)
+    if err != nil {
+        t.Fatal(err)
+    }
+    _, err = builder.Stock("DBS0351471").
+        Type("dicty_stockcenter", "strain").
+        Prop("mutagenesis_method", "REMI").
+        Genotype("axeA-/axeB-").
+        Relationship("derived_from", "DBS0235412").
+        Create(chado)
+    if err != nil {
+        t.Fatal(err)
+    }
+
+    g.Expect(chado).Should(HaveStock("DBS0235412"))
+    g.Expect(chado).ShouldNot(HaveStock("AX4"))
+    g.Expect(chado).Should(HaveStockInCollection("DBS0235412", "Dicty Stock Center"))
+    g.Expect(chado).ShouldNot(HaveStockInCollection("DBS0351471", "Dicty Stock Center"))
+    g.Expect(chado).Should(HaveStockRelationship("DBS0351471", "derived_from", "DBS0235412"))
+    g.Expect(chado).Should(HaveStockRelationship("DBS0351471", "stock_relationship:derived_from", "DBS0235412"))
+    g.Expect(chado).ShouldNot(HaveStockRelationship("DBS0235412", "derived_from", "DBS0351471"))
+    g.Expect(chado).Should(HaveStockGenotype("DBS0351471", "axeA-/axeB-"))
+    g.Expect(chado).ShouldNot(HaveStockGenotype("DBS0235412", "axeA-/axeB-"))
+    g.Expect(chado).Should(HaveStockprop("DBS0351471", "mutagenesis_method", "REMI"))
+
+    m := HaveStockInCollection("DBS0351471", "Dicty Stock Center")
+    m.Match(chado)
+    if msg := m.FailureMessage(chado); !strings.Contains(msg, "found no collections of DBS0351471") {
+        t.Errorf("should have reported the missing collections, got %s", msg)
+    }
+    m = HaveStockRelationship("DBS0351471", "mutant_of", "DBS0235412")
+    m.Match(chado)
+    if msg := m.FailureMessage(chado); !strings.Contains(msg, "DBS0351471 derived_from DBS0235412") {
+        t.Errorf("should have listed the relationships, got %s", msg)
+    }
+}
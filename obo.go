@@ -0,0 +1,412 @@
+package testchado
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/dictybase/testchado/internal/chadodb"
+)
+
+// A [Term] or [Typedef] stanza of an OBO file
+type oboStanza struct {
+	kind string
+	line int
+	tags []oboTag
+}
+
+type oboTag struct {
+	name  string
+	value string
+	line  int
+}
+
+func (s *oboStanza) tag(name string) string {
+	for _, t := range s.tags {
+		if t.name == name {
+			return t.value
+		}
+	}
+	return ""
+}
+
+// Parses an OBO 1.2 or 1.4 file into the header tags and the term and typedef
+// stanzas. Stanzas of any other kind, for example [Instance], are skipped.
+func parseOBO(r io.Reader) (map[string]string, []*oboStanza, error) {
+	header := make(map[string]string)
+	var stanzas []*oboStanza
+	var current *oboStanza
+	inHeader, skip := true, false
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 16*1024*1024)
+	line := 0
+	for scanner.Scan() {
+		line++
+		text := strings.TrimSpace(scanner.Text())
+		if len(text) == 0 || strings.HasPrefix(text, "!") {
+			continue
+		}
+		if strings.HasPrefix(text, "[") && strings.HasSuffix(text, "]") {
+			inHeader, skip = false, false
+			kind := text[1 : len(text)-1]
+			if kind != "Term" && kind != "Typedef" {
+				skip = true
+				continue
+			}
+			current = &oboStanza{kind: kind, line: line}
+			stanzas = append(stanzas, current)
+			continue
+		}
+		if skip {
+			continue
+		}
+		kv := strings.SplitN(text, ":", 2)
+		if len(kv) != 2 {
+			return nil, nil, &ParseError{Format: "obo", Line: line, Err: fmt.Errorf("%s is not in tag: value format", text)}
+		}
+		name, value := strings.TrimSpace(kv[0]), oboValue(kv[1])
+		if inHeader {
+			header[name] = value
+			continue
+		}
+		current.tags = append(current.tags, oboTag{name: name, value: value, line: line})
+	}
+	return header, stanzas, scanner.Err()
+}
+
+// Removes the trailing comment and modifiers of a tag value
+func oboValue(raw string) string {
+	quoted, escaped := false, false
+	for i := 0; i < len(raw); i++ {
+		c := raw[i]
+		switch {
+		case escaped:
+			escaped = false
+		case c == '\\':
+			escaped = true
+		case c == '"':
+			quoted = !quoted
+		case c == '!' && !quoted:
+			raw = raw[:i]
+		}
+	}
+	raw = strings.TrimSpace(raw)
+	if strings.HasSuffix(raw, "}") {
+		if i := strings.LastIndex(raw, "{"); i > 0 {
+			raw = strings.TrimSpace(raw[:i])
+		}
+	}
+	return raw
+}
+
+// Splits a value that begins with a quoted string, for example the def and
+// synonym tags, into the unescaped string and the rest of the value
+func oboQuoted(value string) (string, string, error) {
+	if !strings.HasPrefix(value, "\"") {
+		return "", "", fmt.Errorf("%s does not begin with a quoted string", value)
+	}
+	var b strings.Builder
+	for i := 1; i < len(value); i++ {
+		c := value[i]
+		switch {
+		case c == '\\' && i+1 < len(value):
+			i++
+			switch value[i] {
+			case 'n':
+				b.WriteByte('\n')
+			case 't':
+				b.WriteByte('\t')
+			default:
+				b.WriteByte(value[i])
+			}
+		case c == '"':
+			return b.String(), strings.TrimSpace(value[i+1:]), nil
+		default:
+			b.WriteByte(c)
+		}
+	}
+	return "", "", fmt.Errorf("unterminated quoted string %s", value)
+}
+
+// Removes the escapes of an unquoted value
+func oboUnescape(value string) string {
+	if !strings.Contains(value, "\\") {
+		return value
+	}
+	unescaped, _, _ := oboQuoted("\"" + strings.ReplaceAll(value, "\"", "\\\"") + "\"")
+	return unescaped
+}
+
+// Returns the identifiers of a [DB:accession, ...] list
+func oboXrefList(value string) []string {
+	start, end := strings.Index(value, "["), strings.LastIndex(value, "]")
+	if start == -1 || end < start {
+		return nil
+	}
+	var xrefs []string
+	for _, x := range strings.Split(value[start+1:end], ",") {
+		// an xref might be followed by a quoted description
+		if x = strings.TrimSpace(strings.SplitN(x, " \"", 2)[0]); len(x) > 0 {
+			xrefs = append(xrefs, x)
+		}
+	}
+	return xrefs
+}
+
+// Converts an OBO identifier to a DB:accession one, identifiers without a
+// prefix, usually relations, belong to the _global db
+func oboDbxref(id string) string {
+	if strings.Contains(id, ":") {
+		return id
+	}
+	return "_global:" + id
+}
+
+// Loads the stanzas of an OBO file in the chado schema
+type oboLoader struct {
+	db        *chadodb.DB
+	namespace string
+	// identifier => cvterm_id of the terms and typedefs of the file
+	terms map[string]int64
+}
+
+// Loads an ontology from an OBO 1.2 or 1.4 file in the cv module of chado. The
+// stanzas are mapped as follows
+//
+//	namespace          cv, the default-namespace or ontology header is used if absent
+//	id                 dbxref of the cvterm
+//	name, def          name and definition of the cvterm
+//	is_obsolete        is_obsolete of the cvterm
+//	[Typedef]          cvterm with is_relationshiptype set
+//	is_a               cvterm_relationship typed by relationship:is_a
+//	relationship       cvterm_relationship typed by the typedef
+//	synonym            cvtermsynonym typed by a term of the synonym_type cv
+//	xref, alt_id       cvterm_dbxref, def xrefs have is_for_definition set
+//	comment            cvtermprop typed by cvterm_property_type:comment
+//
+// Terms that already exist, for example from the default fixture, are updated.
+// The whole file is loaded in a single transaction, a failing line is returned as
+// *ParseError.
+func (dbh *DBHelper) LoadOntology(r io.Reader) error {
+	if !dbh.hasLoadedSchema {
+		return ErrSchemaNotLoaded
+	}
+	header, stanzas, err := parseOBO(r)
+	if err != nil {
+		return err
+	}
+	tx, err := dbh.dbhandler.Beginx()
+	if err != nil {
+		return err
+	}
+	l := &oboLoader{
+		db:        chadodb.New(tx),
+		namespace: header["default-namespace"],
+		terms:     make(map[string]int64),
+	}
+	if len(l.namespace) == 0 {
+		l.namespace = header["ontology"]
+	}
+	if err := l.load(stanzas); err != nil {
+		tx.Rollback()
+		return err
+	}
+	return tx.Commit()
+}
+
+func (l *oboLoader) load(stanzas []*oboStanza) error {
+	// the terms are created first as any stanza could refer to the ones after
+	for _, s := range stanzas {
+		if err := l.createTerm(s); err != nil {
+			return &ParseError{Format: "obo", Line: s.line, Err: err}
+		}
+	}
+	for _, s := range stanzas {
+		id := l.terms[s.tag("id")]
+		for _, t := range s.tags {
+			if err := l.createTag(id, t); err != nil {
+				return &ParseError{Format: "obo", Line: t.line, Err: err}
+			}
+		}
+	}
+	return nil
+}
+
+func (l *oboLoader) createTerm(s *oboStanza) error {
+	id, name := s.tag("id"), oboUnescape(s.tag("name"))
+	if len(id) == 0 {
+		return fmt.Errorf("%s stanza without id", s.kind)
+	}
+	if len(name) == 0 {
+		name = id
+	}
+	namespace := s.tag("namespace")
+	if len(namespace) == 0 {
+		namespace = l.namespace
+	}
+	if len(namespace) == 0 {
+		return fmt.Errorf("%s %s has no namespace", s.kind, id)
+	}
+	cvID, err := l.db.Ensure("cv", map[string]interface{}{"name": namespace}, nil)
+	if err != nil {
+		return err
+	}
+	dbxrefID, err := l.db.EnsureDbxref(oboDbxref(id))
+	if err != nil {
+		return err
+	}
+	row := map[string]interface{}{
+		"cv_id":               cvID,
+		"name":                name,
+		"dbxref_id":           dbxrefID,
+		"is_obsolete":         0,
+		"is_relationshiptype": 0,
+	}
+	if s.tag("is_obsolete") == "true" {
+		row["is_obsolete"] = 1
+	}
+	if s.kind == "Typedef" {
+		row["is_relationshiptype"] = 1
+	}
+	if def := s.tag("def"); len(def) > 0 {
+		text, _, err := oboQuoted(def)
+		if err != nil {
+			return err
+		}
+		row["definition"] = text
+	}
+	cvtermID, err := l.db.Lookup("cvterm", map[string]interface{}{"dbxref_id": dbxrefID})
+	if err == chadodb.ErrNotFound {
+		cvtermID, err = l.db.Lookup("cvterm", map[string]interface{}{
+			"cv_id":       cvID,
+			"name":        name,
+			"is_obsolete": row["is_obsolete"],
+		})
+	}
+	switch {
+	case err == chadodb.ErrNotFound:
+		cvtermID, err = l.db.Insert("cvterm", row)
+	case err == nil:
+		err = l.db.Update("cvterm", cvtermID, row)
+	}
+	if err != nil {
+		return err
+	}
+	l.terms[id] = cvtermID
+	return nil
+}
+
+func (l *oboLoader) createTag(id int64, t oboTag) error {
+	switch t.name {
+	case "is_a":
+		typeID, err := l.db.EnsureCvterm("relationship", "is_a")
+		if err != nil {
+			return err
+		}
+		return l.createRelationship(id, typeID, t.value)
+	case "relationship":
+		fields := strings.Fields(t.value)
+		if len(fields) < 2 {
+			return fmt.Errorf("relationship %s is not in type term format", t.value)
+		}
+		typeID, err := l.relation(fields[0])
+		if err != nil {
+			return err
+		}
+		return l.createRelationship(id, typeID, fields[1])
+	case "synonym", "exact_synonym", "narrow_synonym", "broad_synonym", "related_synonym":
+		text, rest, err := oboQuoted(t.value)
+		if err != nil {
+			return err
+		}
+		scope := "RELATED"
+		if fields := strings.Fields(rest); t.name == "synonym" && len(fields) > 0 && !strings.HasPrefix(fields[0], "[") {
+			scope = fields[0]
+		} else if t.name != "synonym" {
+			scope = strings.ToUpper(strings.TrimSuffix(t.name, "_synonym"))
+		}
+		typeID, err := l.db.EnsureCvterm("synonym_type", scope)
+		if err != nil {
+			return err
+		}
+		_, err = l.db.Ensure("cvtermsynonym", map[string]interface{}{"cvterm_id": id, "synonym": text}, map[string]interface{}{"type_id": typeID})
+		return err
+	case "xref", "xref_analog", "alt_id":
+		return l.createDbxref(id, strings.SplitN(t.value, " \"", 2)[0], 0)
+	case "def":
+		_, rest, err := oboQuoted(t.value)
+		if err != nil {
+			return err
+		}
+		for _, x := range oboXrefList(rest) {
+			if err := l.createDbxref(id, x, 1); err != nil {
+				return err
+			}
+		}
+	case "comment":
+		typeID, err := l.db.EnsureCvterm("cvterm_property_type", "comment")
+		if err != nil {
+			return err
+		}
+		// a term has a single comment, a reload replaces its value
+		row := map[string]interface{}{"cvterm_id": id, "type_id": typeID, "rank": 0}
+		propID, err := l.db.Lookup("cvtermprop", row)
+		switch {
+		case err == chadodb.ErrNotFound:
+			row["value"] = oboUnescape(t.value)
+			_, err = l.db.Insert("cvtermprop", row)
+		case err == nil:
+			err = l.db.Update("cvtermprop", propID, map[string]interface{}{"value": oboUnescape(t.value)})
+		}
+		return err
+	}
+	return nil
+}
+
+func (l *oboLoader) createDbxref(id int64, xref string, forDefinition int) error {
+	dbxrefID, err := l.db.EnsureDbxref(oboDbxref(strings.TrimSpace(xref)))
+	if err != nil {
+		return err
+	}
+	_, err = l.db.Ensure("cvterm_dbxref", map[string]interface{}{
+		"cvterm_id":         id,
+		"dbxref_id":         dbxrefID,
+		"is_for_definition": forDefinition,
+	}, nil)
+	return err
+}
+
+func (l *oboLoader) createRelationship(subjectID, typeID int64, object string) error {
+	objectID, err := l.term(object)
+	if err != nil {
+		return err
+	}
+	_, err = l.db.Ensure("cvterm_relationship", map[string]interface{}{
+		"type_id":    typeID,
+		"subject_id": subjectID,
+		"object_id":  objectID,
+	}, nil)
+	return err
+}
+
+// Returns a term of the file or an existing one by its DB:accession
+func (l *oboLoader) term(id string) (int64, error) {
+	if cvtermID, ok := l.terms[id]; ok {
+		return cvtermID, nil
+	}
+	cvtermID, err := l.db.Resolve("cvterm", oboDbxref(id))
+	if err != nil {
+		return 0, fmt.Errorf("unknown term %s", id)
+	}
+	return cvtermID, nil
+}
+
+// Returns a typedef of the file, an existing term of the relationship cv or
+// creates one in it
+func (l *oboLoader) relation(id string) (int64, error) {
+	if cvtermID, err := l.term(id); err == nil {
+		return cvtermID, nil
+	}
+	return l.db.EnsureCvterm("relationship", id)
+}
@@ -0,0 +1,124 @@
+package testchado
+
+import (
+    "errors"
+    "os"
+    "strings"
+    "testing"
+)
+
+func TestLoadOntology(t *testing.T) {
+    dbm := NewTestChado(t)
+    _ = dbm.LoadDefaultFixture()
+    fh, err := os.Open("testdata/phenotype.obo")
+    if err != nil {
+        t.Fatal(err)
+    }
+    defer fh.Close()
+    if err := dbm.LoadOntology(fh); err != nil {
+        t.Fatalf("should have loaded obo file: %s", err)
+    }
+
+    type entries struct{ Counter int }
+    counts := map[string]string{
+        "cvterm":     "SELECT count(*) counter FROM cvterm JOIN cv ON cv.cv_id = cvterm.cv_id WHERE cv.name = 'Dicty_Phenotypes'",
+        "namespace":  "SELECT count(*) counter FROM cvterm JOIN cv ON cv.cv_id = cvterm.cv_id WHERE cv.name = 'dicty_sporulation'",
+        "is_a":       "SELECT count(*) counter FROM cvterm_relationship cr JOIN cvterm ON cvterm.cvterm_id = cr.type_id WHERE cvterm.name = 'is_a'",
+        "part_of":    "SELECT count(*) counter FROM cvterm_relationship cr JOIN cvterm ON cvterm.cvterm_id = cr.type_id WHERE cvterm.name = 'part_of' AND cvterm.is_relationshiptype = 1",
+        "synonym":    "SELECT count(*) counter FROM cvtermsynonym JOIN cvterm ON cvterm.cvterm_id = cvtermsynonym.type_id WHERE cvterm.name IN ('EXACT', 'RELATED')",
+        "xref":       "SELECT count(*) counter FROM cvterm_dbxref JOIN dbxref ON dbxref.dbxref_id = cvterm_dbxref.dbxref_id WHERE is_for_definition = 0 AND dbxref.accession = '0000123'",
+        "def_xref":   "SELECT count(*) counter FROM cvterm_dbxref WHERE is_for_definition = 1",
+        "obsolete":   "SELECT count(*) counter FROM cvterm WHERE name = 'obsolete spore color' AND is_obsolete = 1",
+        "comment":    "SELECT count(*) counter FROM cvtermprop WHERE value = 'Spores look wrong ! really.'",
+        "definition": "SELECT count(*) counter FROM cvterm WHERE definition = 'Any observable characteristic of an amoeba.'",
+    }
+    expected := map[string]int{
+        "cvterm":     4,
+        "namespace":  1,
+        "is_a":       2,
+        "part_of":    1,
+        "synonym":    2,
+        "xref":       1,
+        "def_xref":   2,
+        "obsolete":   1,
+        "comment":    1,
+        "definition": 1,
+    }
+    for name, query := range counts {
+        e := entries{}
+        if err := dbm.DBHandle().Get(&e, query); err != nil {
+            t.Errorf("should have executed the %s query %s", name, err)
+        }
+        if e.Counter != expected[name] {
+            t.Errorf("should have %d %s rows, got %d", expected[name], name, e.Counter)
+        }
+    }
+}
+
+func TestLoadOntologyUpdate(t *testing.T) {
+    dbm := NewTestChado(t)
+    _ = dbm.LoadDefaultFixture()
+    content := `default-namespace: sequence
+
+[Term]
+id: SO:0000704
+name: gene
+def: "A region that encodes a product." []
+`
+    if err := dbm.LoadOntology(strings.NewReader(content)); err != nil {
+        t.Fatalf("should have loaded obo file: %s", err)
+    }
+    type entries struct{ Counter int }
+    e := entries{}
+    err := dbm.DBHandle().Get(&e, "SELECT count(*) counter FROM cvterm JOIN cv ON cv.cv_id = cvterm.cv_id WHERE cv.name = 'sequence' AND cvterm.name = 'gene'")
+    if err != nil {
+        t.Errorf("should have executed the query %s", err)
+    }
+    if e.Counter != 1 {
+        t.Errorf("should have updated the existing gene term, got %d", e.Counter)
+    }
+}
+
+func TestLoadOntologyTwice(t *testing.T) {
+    dbm := NewTestChado(t)
+    _ = dbm.LoadDefaultFixture()
+    for i := 0; i < 2; i++ {
+        fh, err := os.Open("testdata/phenotype.obo")
+        if err != nil {
+            t.Fatal(err)
+        }
+        err = dbm.LoadOntology(fh)
+        fh.Close()
+        if err != nil {
+            t.Fatalf("should have loaded obo file %d times: %s", i+1, err)
+        }
+    }
+    type entries struct{ Counter int }
+    e := entries{}
+    if err := dbm.DBHandle().Get(&e, "SELECT count(*) counter FROM cvtermprop WHERE value = 'Spores look wrong ! really.'"); err != nil {
+        t.Errorf("should have executed the query %s", err)
+    }
+    if e.Counter != 1 {
+        t.Errorf("should have kept a single comment, got %d", e.Counter)
+    }
+}
+
+func TestLoadOntologyError(t *testing.T) {
+    dbm := NewTestChado(t)
+    _ = dbm.LoadDefaultFixture()
+    content := `default-namespace: test
+
+[Term]
+id: TEST:1
+name: one
+is_a: TEST:2
+`
+    err := dbm.LoadOntology(strings.NewReader(content))
+    var perr *ParseError
+    if !errors.As(err, &perr) {
+        t.Fatalf("should have returned a parse error: %s", err)
+    }
+    if perr.Line != 6 {
+        t.Errorf("should have reported line 6, got %d", perr.Line)
+    }
+}
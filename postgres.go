@@ -1,47 +1,82 @@
 package testchado
 
 import (
-	"bytes"
+	"fmt"
 	"log"
-	"math/rand"
-	"time"
+	"strings"
 
 	"github.com/jinzhu/gorm"
 	"github.com/jmoiron/sqlx"
-	_ "github.com/lib/pq"
+	"github.com/lib/pq"
 )
 
-// Generates a random string between a range(min and max) of length
-func RandomString(min, max int) string {
-	alphanum := []byte("abcdefghijklmnopqrstuvwxyz")
-	rand.Seed(time.Now().UTC().UnixNano())
-	size := min + rand.Intn(max-min)
-	b := make([]byte, size)
-	alen := len(alphanum)
-	for i := 0; i < size; i++ {
-		pos := rand.Intn(alen)
-		b[i] = alphanum[pos]
-	}
-	return string(b)
-}
+// Moves every sequence of the current schema past the largest primary key
+// of its table
+const syncSequences = `
+DO $$
+DECLARE r record;
+BEGIN
+	FOR r IN SELECT table_name, column_name, pg_get_serial_sequence(quote_ident(table_name), column_name) seq
+		FROM information_schema.columns
+		WHERE table_schema = current_schema() AND column_default LIKE 'nextval(%'
+	LOOP
+		EXECUTE format('SELECT setval(%L, COALESCE(MAX(%I), 0) + 1, false) FROM %I', r.seq, r.column_name, r.table_name);
+	END LOOP;
+END
+$$`
 
 // A type specific for postgresql backend
 type Postgres struct {
 	*DBHelper
-	Schema string
+	Schema    string
+	snapshots map[string]*postgresSnapshot
+}
+
+// A copy of every table kept in a separate schema along with the
+// state of the sequences
+type postgresSnapshot struct {
+	schema    string
+	sequences map[string]sequenceState
+	presets   map[string]bool
+}
+
+type sequenceState struct {
+	LastValue int64 `db:"last_value"`
+	IsCalled  bool  `db:"is_called"`
 }
 
 // Get an instance of postgres DBManager.
 // For details about datasource look here http://godoc.org/github.com/lib/pq
 func NewPostgresManager(datasource string) *Postgres {
-	gm, err := gorm.Open("postgres", datasource)
+	postgres, err := NewPostgresManagerE(datasource)
 	if err != nil {
 		log.Fatal(err)
 	}
+	return postgres
+}
+
+// Get an instance of postgres DBManager, returns an error instead of
+// exiting if the database could not be reached
+func NewPostgresManagerE(datasource string) (*Postgres, error) {
+	gm, err := gorm.Open("postgres", datasource)
+	if err != nil {
+		return nil, err
+	}
+	if err := gm.DB().Ping(); err != nil {
+		gm.DB().Close()
+		return nil, err
+	}
 	gm.SingularTable(true)
 	sqlx := sqlx.NewDb(gm.DB(), "postgres")
-	schema := RandomString(9, 10)
-	return &Postgres{&DBHelper{dbsource: datasource, driver: "postgres", dbhandler: sqlx, gormHandler: &gm}, schema}
+	seed, err := newSeed()
+	if err != nil {
+		gm.DB().Close()
+		return nil, err
+	}
+	dbh := &DBHelper{dbsource: datasource, driver: "postgres", dbhandler: sqlx, gormHandler: &gm}
+	dbh.reseed(seed)
+	// the schema name is derived from the seed, so it could be reproduced
+	return &Postgres{DBHelper: dbh, Schema: randomString(dbh.rnd, 9, 10)}, nil
 }
 
 func (postgres *Postgres) Database() string {
@@ -50,44 +85,54 @@ func (postgres *Postgres) Database() string {
 
 func (postgres *Postgres) DeploySchema() error {
 	schema := postgres.Schema
-	// Setup the schema
-	buff := bytes.NewBufferString("DROP SCHEMA IF EXISTS " + schema + " CASCADE;\n")
-	_, err := buff.WriteString("CREATE SCHEMA " + schema + ";\n")
-	if err != nil {
-		return err
-	}
-	_, err = buff.WriteString("SET search_path TO " + schema + ";\n")
-	if err != nil {
-		return err
-	}
 	//Now get schema definition
 	content, err := postgres.SchemaDDL()
 	if err != nil {
 		return err
 	}
-	buff.Write(content.Bytes())
 
 	// Do everything in transaction
-	tx := postgres.DBHandle().MustBegin()
+	tx, err := postgres.DBHandle().Begin()
+	if err != nil {
+		return err
+	}
+	// Setup the schema
+	setup := []string{
+		"DROP SCHEMA IF EXISTS " + schema + " CASCADE",
+		"CREATE SCHEMA " + schema,
+		"SET search_path TO " + schema,
+	}
+	for _, stmt := range setup {
+		if _, err := tx.Exec(stmt); err != nil {
+			tx.Rollback()
+			return &StatementError{Source: "DeploySchema", Statement: stmt, Err: err}
+		}
+	}
 	// Load schema in postgresql
-	_ = tx.MustExec(buff.String())
+	if err := execStatements(tx, "chado."+postgres.Driver(), content.String()); err != nil {
+		tx.Rollback()
+		return err
+	}
 	err = tx.Commit()
 	if err != nil {
 		return err
 	}
 	postgres.DBHelper.hasLoadedSchema = true
+	postgres.DBHelper.loadedPresets = nil
 	return nil
 }
 
 func (postgres *Postgres) DropSchema() error {
-	tx := postgres.DBHandle().MustBegin()
-	_ = tx.MustExec("DROP SCHEMA IF EXISTS " + postgres.Schema + " CASCADE")
-	err := tx.Commit()
-	if err != nil {
+	if err := postgres.discardSnapshots(); err != nil {
 		return err
 	}
-	postgres.Schema = RandomString(9, 10)
+	stmt := "DROP SCHEMA IF EXISTS " + postgres.Schema + " CASCADE"
+	if _, err := postgres.DBHandle().Exec(stmt); err != nil {
+		return &StatementError{Source: "DropSchema", Statement: stmt, Err: err}
+	}
+	postgres.Schema = randomString(postgres.rnd, 9, 10)
 	postgres.DBHelper.hasLoadedSchema = false
+	postgres.DBHelper.loadedPresets = nil
 	return nil
 }
 
@@ -103,3 +148,165 @@ func (postgres *Postgres) ResetSchema() error {
 	postgres.DBHelper.hasLoadedSchema = true
 	return nil
 }
+
+// Copies every table of the chado schema into a separate schema and records the
+// state of all sequences. An existing snapshot with the same name is replaced.
+func (postgres *Postgres) Snapshot(name string) error {
+	if !postgres.hasLoadedSchema {
+		return ErrSchemaNotLoaded
+	}
+	tables, err := postgres.tables()
+	if err != nil {
+		return err
+	}
+	dbh := postgres.DBHandle()
+	var seqs []string
+	err = dbh.Select(&seqs, "SELECT sequence_name FROM information_schema.sequences WHERE sequence_schema = $1", postgres.Schema)
+	if err != nil {
+		return err
+	}
+	snap := &postgresSnapshot{
+		sequences: make(map[string]sequenceState),
+		presets:   copyPresets(postgres.loadedPresets),
+	}
+	if old, ok := postgres.snapshots[name]; ok {
+		snap.schema = old.schema
+	} else {
+		// independent of the chado schema name, which might already take up
+		// the whole identifier length
+		snap.schema = "tc_snap_" + randomString(postgres.rnd, 10, 11)
+	}
+
+	tx, err := dbh.Beginx()
+	if err != nil {
+		return err
+	}
+	stmts := []string{
+		"DROP SCHEMA IF EXISTS " + pq.QuoteIdentifier(snap.schema) + " CASCADE",
+		"CREATE SCHEMA " + pq.QuoteIdentifier(snap.schema),
+	}
+	for _, t := range tables {
+		stmts = append(stmts, fmt.Sprintf("CREATE TABLE %s AS SELECT * FROM %s", postgres.qualify(snap.schema, t), postgres.qualify(postgres.Schema, t)))
+	}
+	for _, stmt := range stmts {
+		if _, err := tx.Exec(stmt); err != nil {
+			tx.Rollback()
+			return &StatementError{Source: "Snapshot", Statement: stmt, Err: err}
+		}
+	}
+	for _, seq := range seqs {
+		var state sequenceState
+		if err := tx.Get(&state, "SELECT last_value, is_called FROM "+postgres.qualify(postgres.Schema, seq)); err != nil {
+			tx.Rollback()
+			return err
+		}
+		snap.sequences[seq] = state
+	}
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+	if postgres.snapshots == nil {
+		postgres.snapshots = make(map[string]*postgresSnapshot)
+	}
+	postgres.snapshots[name] = snap
+	return nil
+}
+
+// Replaces the content of every table with the copy saved by Snapshot and
+// resets the sequences
+func (postgres *Postgres) Restore(name string) error {
+	snap, ok := postgres.snapshots[name]
+	if !ok {
+		return ErrUnknownSnapshot
+	}
+	tables, err := postgres.tables()
+	if err != nil {
+		return err
+	}
+	refs, err := postgres.references()
+	if err != nil {
+		return err
+	}
+	var qualified []string
+	for _, t := range tables {
+		qualified = append(qualified, postgres.qualify(postgres.Schema, t))
+	}
+	stmts := []string{"TRUNCATE " + strings.Join(qualified, ", ")}
+	for _, t := range sortTables(tables, refs) {
+		stmts = append(stmts, fmt.Sprintf("INSERT INTO %s SELECT * FROM %s", postgres.qualify(postgres.Schema, t), postgres.qualify(snap.schema, t)))
+	}
+
+	tx, err := postgres.DBHandle().Beginx()
+	if err != nil {
+		return err
+	}
+	for _, stmt := range stmts {
+		if _, err := tx.Exec(stmt); err != nil {
+			tx.Rollback()
+			return &StatementError{Source: "Restore", Statement: stmt, Err: err}
+		}
+	}
+	for seq, state := range snap.sequences {
+		_, err := tx.Exec("SELECT setval($1, $2, $3)", postgres.qualify(postgres.Schema, seq), state.LastValue, state.IsCalled)
+		if err != nil {
+			tx.Rollback()
+			return err
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+	postgres.DBHelper.hasLoadedSchema = true
+	postgres.DBHelper.loadedPresets = copyPresets(snap.presets)
+	return nil
+}
+
+func (postgres *Postgres) discardSnapshots() error {
+	for name, snap := range postgres.snapshots {
+		stmt := "DROP SCHEMA IF EXISTS " + pq.QuoteIdentifier(snap.schema) + " CASCADE"
+		if _, err := postgres.DBHandle().Exec(stmt); err != nil {
+			return &StatementError{Source: "DropSchema", Statement: stmt, Err: err}
+		}
+		delete(postgres.snapshots, name)
+	}
+	return nil
+}
+
+// Name of all tables in the chado schema
+func (postgres *Postgres) tables() ([]string, error) {
+	var tables []string
+	err := postgres.DBHandle().Select(
+		&tables,
+		"SELECT table_name FROM information_schema.tables WHERE table_schema = $1 AND table_type = 'BASE TABLE'",
+		postgres.Schema,
+	)
+	return tables, err
+}
+
+// Foreign key references between tables of the chado schema, table => referenced tables
+func (postgres *Postgres) references() (map[string][]string, error) {
+	type ref struct {
+		Tname string
+		Rname string
+	}
+	var rs []ref
+	err := postgres.DBHandle().Select(&rs, `
+        SELECT src.relname tname, dest.relname rname FROM pg_constraint c
+        JOIN pg_class src ON src.oid = c.conrelid
+        JOIN pg_class dest ON dest.oid = c.confrelid
+        JOIN pg_namespace n ON n.oid = src.relnamespace
+        WHERE c.contype = 'f' AND n.nspname = $1
+        `, postgres.Schema)
+	if err != nil {
+		return nil, err
+	}
+	refs := make(map[string][]string)
+	for _, r := range rs {
+		refs[r.Tname] = append(refs[r.Tname], r.Rname)
+	}
+	return refs, nil
+}
+
+func (postgres *Postgres) qualify(schema, name string) string {
+	return pq.QuoteIdentifier(schema) + "." + pq.QuoteIdentifier(name)
+}
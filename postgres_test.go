@@ -168,3 +168,69 @@ func TestPostgresLoadPresetFixture(t *testing.T) {
         t.Error("should have 13 cvterms")
     }
 }
+
+func TestPostgresSnapshot(t *testing.T) {
+    if !CheckPostgresEnv() {
+        t.Skip("postgres environment variable TC_DSOURCE is not set")
+    }
+    ds := GetDataSource()
+    dbm := NewPostgresManager(ds)
+    if err := dbm.Snapshot("fixture"); err != ErrSchemaNotLoaded {
+        t.Errorf("should not take snapshot without schema: %s", err)
+    }
+    defer dbm.DropSchema()
+    _ = dbm.DeploySchema()
+    _ = dbm.LoadDefaultFixture()
+    if err := dbm.Snapshot("fixture"); err != nil {
+        t.Fatalf("should have taken snapshot: %s", err)
+    }
+
+    type entries struct{ Counter int }
+    e := entries{}
+    sqlx := dbm.DBHandle()
+    sqlx.MustExec("DELETE FROM organism")
+    if err := dbm.Restore("fixture"); err != nil {
+        t.Fatalf("should have restored snapshot: %s", err)
+    }
+    if err := sqlx.Get(&e, "SELECT count(*) counter FROM organism"); err != nil {
+        t.Errorf("should have executed the query %s", err)
+    }
+    if e.Counter != 12 {
+        t.Errorf("should have restored 12 organisms, got %d", e.Counter)
+    }
+    if err := dbm.Restore("pristine"); err != ErrUnknownSnapshot {
+        t.Errorf("should not restore unknown snapshot: %s", err)
+    }
+}
+
+func TestPostgresSnapshotLongTestName(t *testing.T) {
+    if !CheckPostgresEnv() {
+        t.Skip("postgres environment variable TC_DSOURCE is not set")
+    }
+    t.Run("AVeryLongSubtestNameThatFillsTheWholePostgresqlIdentifier", func(t *testing.T) {
+        dbm := NewTestChado(t)
+        if n := len(dbm.(*Postgres).Schema); n != maxIdentifierLen {
+            t.Fatalf("should have a schema name of %d bytes, got %d", maxIdentifierLen, n)
+        }
+        _ = dbm.LoadDefaultFixture()
+        for _, name := range []string{"first", "second"} {
+            if err := dbm.Snapshot(name); err != nil {
+                t.Fatalf("should have taken snapshot %s: %s", name, err)
+            }
+        }
+        dbm.DBHandle().MustExec("DELETE FROM organism")
+        for _, name := range []string{"first", "second"} {
+            if err := dbm.Restore(name); err != nil {
+                t.Errorf("should have restored snapshot %s: %s", name, err)
+            }
+        }
+        type entries struct{ Counter int }
+        e := entries{}
+        if err := dbm.DBHandle().Get(&e, "SELECT count(*) counter FROM organism"); err != nil {
+            t.Errorf("should have executed the query %s", err)
+        }
+        if e.Counter != 12 {
+            t.Errorf("should have restored 12 organisms, got %d", e.Counter)
+        }
+    })
+}
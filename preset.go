@@ -0,0 +1,220 @@
+package testchado
+
+import (
+	"archive/zip"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// Matches any error returned by LoadPresetFixture for a preset that does not exist
+var ErrUnknownPreset = errors.New("unknown preset fixture")
+
+// UnknownPresetError reports a preset that could not be found along with the
+// list of presets that are available.
+type UnknownPresetError struct {
+	Name      string
+	Available []string
+}
+
+func (e *UnknownPresetError) Error() string {
+	return fmt.Sprintf("unknown preset fixture %q, available presets are %s", e.Name, strings.Join(e.Available, ", "))
+}
+
+func (e *UnknownPresetError) Is(target error) bool {
+	return target == ErrUnknownPreset
+}
+
+// A named fixture that could be loaded by LoadPresetFixture
+type preset struct {
+	name     string
+	fsys     fs.FS
+	requires []string
+}
+
+// Presets registered in addition to the bundled ones
+var registry = struct {
+	sync.RWMutex
+	presets map[string]preset
+}{presets: make(map[string]preset)}
+
+// RegisterPreset makes the fixture file name.sql from fsys available to
+// LoadPresetFixture under the given name. The presets in requires are loaded
+// before it, unless they were already loaded in the same schema. Registering
+// an existing name, including a bundled one, replaces it and closes the file
+// system of the replaced preset if it is an io.Closer.
+func RegisterPreset(name string, fsys fs.FS, requires ...string) error {
+	if len(name) == 0 {
+		return errors.New("preset name is empty")
+	}
+	if _, err := fs.Stat(fsys, name+".sql"); err != nil {
+		return fmt.Errorf("unable to register preset %s: %s", name, err)
+	}
+	registry.Lock()
+	defer registry.Unlock()
+	// only a closer is compared, a file system like fstest.MapFS is not comparable
+	if old, ok := registry.presets[name]; ok {
+		if c, ok := old.fsys.(io.Closer); ok && old.fsys != fsys {
+			c.Close()
+		}
+	}
+	registry.presets[name] = preset{name: name, fsys: fsys, requires: requires}
+	return nil
+}
+
+// Removes registered presets, the bundled ones of the same name become
+// available again. Meant for tests that register presets.
+func unregisterPresets(names ...string) {
+	registry.Lock()
+	defer registry.Unlock()
+	for _, n := range names {
+		if p, ok := registry.presets[n]; ok {
+			if c, ok := p.fsys.(io.Closer); ok {
+				c.Close()
+			}
+			delete(registry.presets, n)
+		}
+	}
+}
+
+// RegisterPresetDir registers the fixture file name.sql from a directory
+func RegisterPresetDir(name, dir string, requires ...string) error {
+	return RegisterPreset(name, os.DirFS(dir), requires...)
+}
+
+// RegisterPresetZip registers the fixture file name.sql from a zip archive
+func RegisterPresetZip(name, file string, requires ...string) error {
+	zr, err := zip.OpenReader(file)
+	if err != nil {
+		return err
+	}
+	if err := RegisterPreset(name, zr, requires...); err != nil {
+		zr.Close()
+		return err
+	}
+	return nil
+}
+
+// List the name of presets available in a file system, every file with
+// .sql extension is a preset
+func presetNames(fsys fs.FS) ([]string, error) {
+	files, err := fs.Glob(fsys, "*.sql")
+	if err != nil {
+		return nil, err
+	}
+	var names []string
+	for _, f := range files {
+		names = append(names, strings.TrimSuffix(f, ".sql"))
+	}
+	sort.Strings(names)
+	return names, nil
+}
+
+// Returns all available presets, the registered ones take precedence
+// over the bundled ones
+func availablePresets() (map[string]preset, error) {
+	fsys, err := PresetFS()
+	if err != nil {
+		return nil, err
+	}
+	names, err := presetNames(fsys)
+	if err != nil {
+		return nil, err
+	}
+	all := make(map[string]preset)
+	for _, n := range names {
+		all[n] = preset{name: n, fsys: fsys}
+	}
+	registry.RLock()
+	defer registry.RUnlock()
+	for n, p := range registry.presets {
+		all[n] = p
+	}
+	return all, nil
+}
+
+func sortedPresetNames(all map[string]preset) []string {
+	var names []string
+	for n := range all {
+		names = append(names, n)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Returns the preset along with all of its dependencies, in the
+// order they have to be loaded
+func resolvePreset(name string) ([]preset, error) {
+	all, err := availablePresets()
+	if err != nil {
+		return nil, err
+	}
+	var order []preset
+	state := make(map[string]int) // 1: visiting, 2: resolved
+	var visit func(string, []string) error
+	visit = func(n string, path []string) error {
+		switch state[n] {
+		case 1:
+			return fmt.Errorf("preset dependency cycle %s", strings.Join(append(path, n), " -> "))
+		case 2:
+			return nil
+		}
+		p, ok := all[n]
+		if !ok {
+			return &UnknownPresetError{Name: n, Available: sortedPresetNames(all)}
+		}
+		state[n] = 1
+		for _, r := range p.requires {
+			if err := visit(r, append(path, n)); err != nil {
+				return err
+			}
+		}
+		state[n] = 2
+		order = append(order, p)
+		return nil
+	}
+	if err := visit(name, nil); err != nil {
+		return nil, err
+	}
+	return order, nil
+}
+
+// Loads one of the preset fixture that comes bundled with testchado or registered
+// through RegisterPreset. Its dependencies are loaded first, unless they were loaded
+// earlier in the same schema.
+func (dbh *DBHelper) LoadPresetFixture(name string) error {
+	if !dbh.hasLoadedSchema {
+		return ErrSchemaNotLoaded
+	}
+	order, err := resolvePreset(name)
+	if err != nil {
+		return err
+	}
+	for _, p := range order {
+		if p.name != name && dbh.loadedPresets[p.name] {
+			continue
+		}
+		if err := dbh.LoadFixtureFS(p.fsys, p.name+".sql"); err != nil {
+			return err
+		}
+		if dbh.loadedPresets == nil {
+			dbh.loadedPresets = make(map[string]bool)
+		}
+		dbh.loadedPresets[p.name] = true
+	}
+	return nil
+}
+
+// List the name of all preset fixtures that could be loaded by LoadPresetFixture
+func (dbh *DBHelper) ListPresets() ([]string, error) {
+	all, err := availablePresets()
+	if err != nil {
+		return nil, err
+	}
+	return sortedPresetNames(all), nil
+}
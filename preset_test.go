@@ -0,0 +1,164 @@
+package testchado
+
+import (
+    "archive/zip"
+    "errors"
+    "os"
+    "path/filepath"
+    "strings"
+    "testing"
+    "testing/fstest"
+)
+
+func TestListPresets(t *testing.T) {
+    dbm := NewSQLiteManager()
+    names, err := dbm.ListPresets()
+    if err != nil {
+        t.Fatalf("should have listed the presets: %s", err)
+    }
+    for _, name := range []string{"cvprop", "default", "eco"} {
+        if !strings.Contains(strings.Join(names, ","), name) {
+            t.Errorf("should have listed %s preset, got %v", name, names)
+        }
+    }
+}
+
+func TestUnknownPreset(t *testing.T) {
+    dbm := NewSQLiteManager()
+    _ = dbm.DeploySchema()
+    err := dbm.LoadPresetFixture("cvprops")
+    if !errors.Is(err, ErrUnknownPreset) {
+        t.Fatalf("should have returned unknown preset error: %s", err)
+    }
+    var perr *UnknownPresetError
+    if !errors.As(err, &perr) {
+        t.Fatal("should have returned *UnknownPresetError")
+    }
+    if perr.Name != "cvprops" || len(perr.Available) < 3 {
+        t.Errorf("should have reported the name and available presets: %s", perr)
+    }
+}
+
+func TestRegisterPreset(t *testing.T) {
+    dir := t.TempDir()
+    strains := "INSERT INTO organism (genus, species, common_name) VALUES ('Dictyostelium', 'firmibasis', 'firmi');\n"
+    if err := os.WriteFile(filepath.Join(dir, "dicty_strains.sql"), []byte(strains), 0644); err != nil {
+        t.Fatal(err)
+    }
+    t.Cleanup(func() { unregisterPresets("dicty_strains", "dicty_pubs") })
+    if err := RegisterPresetDir("dicty_strains", dir, "default"); err != nil {
+        t.Fatalf("should have registered preset: %s", err)
+    }
+    if err := RegisterPresetDir("missing", dir); err == nil {
+        t.Error("should not register a preset without fixture file")
+    }
+
+    zfile := filepath.Join(dir, "presets.zip")
+    f, err := os.Create(zfile)
+    if err != nil {
+        t.Fatal(err)
+    }
+    zw := zip.NewWriter(f)
+    w, _ := zw.Create("dicty_pubs.sql")
+    w.Write([]byte("INSERT INTO pub (uniquename, type_id) SELECT 'PMID:1', cvterm_id FROM cvterm WHERE name = 'gene';\n"))
+    zw.Close()
+    f.Close()
+    if err := RegisterPresetZip("dicty_pubs", zfile, "dicty_strains"); err != nil {
+        t.Fatalf("should have registered zip preset: %s", err)
+    }
+
+    dbm := NewSQLiteManager()
+    names, _ := dbm.ListPresets()
+    if !strings.Contains(strings.Join(names, ","), "default,dicty_pubs,dicty_strains,eco") {
+        t.Errorf("should have listed registered presets, got %v", names)
+    }
+    _ = dbm.DeploySchema()
+    if err := dbm.LoadPresetFixture("dicty_pubs"); err != nil {
+        t.Fatalf("should have loaded preset with its dependencies: %s", err)
+    }
+    type entries struct{ Counter int }
+    e := entries{}
+    sqlx := dbm.DBHandle()
+    if err := sqlx.Get(&e, "SELECT count(*) counter FROM organism"); err != nil {
+        t.Errorf("should have executed the query %s", err)
+    }
+    if e.Counter != 13 {
+        t.Errorf("should have 13 organisms, got %d", e.Counter)
+    }
+    if err := sqlx.Get(&e, "SELECT count(*) counter FROM pub"); err != nil {
+        t.Errorf("should have executed the query %s", err)
+    }
+    if e.Counter != 1 {
+        t.Errorf("should have 1 pub, got %d", e.Counter)
+    }
+}
+
+func TestPresetDependencyCycle(t *testing.T) {
+    fsys := fstest.MapFS{
+        "cycle_a.sql": &fstest.MapFile{Data: []byte("SELECT 1;")},
+        "cycle_b.sql": &fstest.MapFile{Data: []byte("SELECT 1;")},
+    }
+    _ = RegisterPreset("cycle_a", fsys, "cycle_b")
+    _ = RegisterPreset("cycle_b", fsys, "cycle_a")
+    t.Cleanup(func() { unregisterPresets("cycle_a", "cycle_b") })
+    dbm := NewSQLiteManager()
+    _ = dbm.DeploySchema()
+    err := dbm.LoadPresetFixture("cycle_a")
+    if err == nil || !strings.Contains(err.Error(), "cycle_a -> cycle_b -> cycle_a") {
+        t.Errorf("should have reported the dependency cycle: %s", err)
+    }
+}
+
+func TestUnregisterPreset(t *testing.T) {
+    fsys := fstest.MapFS{"scratch.sql": &fstest.MapFile{Data: []byte("SELECT 1;")}}
+    if err := RegisterPreset("scratch", fsys); err != nil {
+        t.Fatalf("should have registered preset: %s", err)
+    }
+    unregisterPresets("scratch")
+    dbm := NewSQLiteManager()
+    _ = dbm.DeploySchema()
+    if err := dbm.LoadPresetFixture("scratch"); !errors.Is(err, ErrUnknownPreset) {
+        t.Errorf("should not load an unregistered preset: %s", err)
+    }
+}
+
+// A file system that records whether it was closed
+type closingFS struct {
+    fstest.MapFS
+    closed bool
+}
+
+func (c *closingFS) Close() error {
+    c.closed = true
+    return nil
+}
+
+func TestRegisterPresetReplace(t *testing.T) {
+    t.Cleanup(func() { unregisterPresets("scratch") })
+    mapfs := fstest.MapFS{"scratch.sql": &fstest.MapFile{Data: []byte("SELECT 1;")}}
+    for i := 0; i < 2; i++ {
+        if err := RegisterPreset("scratch", mapfs); err != nil {
+            t.Fatalf("should have registered preset: %s", err)
+        }
+    }
+    first := &closingFS{MapFS: fstest.MapFS{"scratch.sql": &fstest.MapFile{Data: []byte("SELECT 1;")}}}
+    if err := RegisterPreset("scratch", first); err != nil {
+        t.Fatalf("should have registered preset: %s", err)
+    }
+    if err := RegisterPreset("scratch", first); err != nil {
+        t.Fatalf("should have registered preset again: %s", err)
+    }
+    if first.closed {
+        t.Error("should not close the file system that is registered again")
+    }
+    second := &closingFS{MapFS: fstest.MapFS{"scratch.sql": &fstest.MapFile{Data: []byte("SELECT 2;")}}}
+    if err := RegisterPreset("scratch", second); err != nil {
+        t.Fatalf("should have replaced preset: %s", err)
+    }
+    if !first.closed {
+        t.Error("should have closed the file system of the replaced preset")
+    }
+    if second.closed {
+        t.Error("should not close the file system of the registered preset")
+    }
+}
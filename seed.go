@@ -0,0 +1,104 @@
+package testchado
+
+import (
+	"fmt"
+	"hash/fnv"
+	"log"
+	"math/rand"
+	"os"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Name of the env variable that seeds the random sources of the managers, for
+// example the one that names the postgres schemas. A seed drawn from the clock is
+// logged once per process, so that a failing run could be reproduced with the
+// same schema names.
+const SeedEnv = "TC_SEED"
+
+var (
+	seedOnce sync.Once
+	seedBase int64
+	seedErr  error
+	// number of managers seeded so far
+	seedCount int64
+	// source of RandomString, separate from the global one of math/rand
+	stringRand struct {
+		sync.Mutex
+		rnd *rand.Rand
+	}
+)
+
+// Returns the seed of the process, either from TC_SEED or the current time
+func baseSeed() (int64, error) {
+	seedOnce.Do(func() {
+		if v := os.Getenv(SeedEnv); len(v) > 0 {
+			seedBase, seedErr = strconv.ParseInt(v, 10, 64)
+			if seedErr != nil {
+				seedErr = fmt.Errorf("invalid %s %q: %s", SeedEnv, v, seedErr)
+			}
+		} else {
+			seedBase = time.Now().UnixNano()
+			// shown by go test for a failing package, NewDBManager gives no
+			// other chance to report it
+			log.Printf("testchado: reproduce this run with %s=%d", SeedEnv, seedBase)
+		}
+		stringRand.rnd = rand.New(rand.NewSource(seedBase))
+	})
+	return seedBase, seedErr
+}
+
+// Returns the seed of a new manager, every manager of the process gets the
+// next one after the process seed
+func newSeed() (int64, error) {
+	base, err := baseSeed()
+	if err != nil {
+		return 0, err
+	}
+	return base + atomic.AddInt64(&seedCount, 1) - 1, nil
+}
+
+// Returns the seed of a test derived from the process seed and the test name,
+// so it does not depend on the order parallel tests start in
+func testSeed(name string) (int64, error) {
+	base, err := baseSeed()
+	if err != nil {
+		return 0, err
+	}
+	h := fnv.New64a()
+	h.Write([]byte(name))
+	return base ^ int64(h.Sum64()), nil
+}
+
+// Generates a random string between a range(min and max) of length
+func RandomString(min, max int) string {
+	baseSeed()
+	stringRand.Lock()
+	defer stringRand.Unlock()
+	return randomString(stringRand.rnd, min, max)
+}
+
+func randomString(rnd *rand.Rand, min, max int) string {
+	alphanum := []byte("abcdefghijklmnopqrstuvwxyz")
+	size := min + rnd.Intn(max-min)
+	b := make([]byte, size)
+	alen := len(alphanum)
+	for i := 0; i < size; i++ {
+		pos := rnd.Intn(alen)
+		b[i] = alphanum[pos]
+	}
+	return string(b)
+}
+
+// The seed of the random source of the manager
+func (dbh *DBHelper) Seed() int64 {
+	return dbh.seed
+}
+
+// Resets the random source of the manager to the seed
+func (dbh *DBHelper) reseed(seed int64) {
+	dbh.seed = seed
+	dbh.rnd = rand.New(rand.NewSource(seed))
+}
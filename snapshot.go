@@ -0,0 +1,51 @@
+package testchado
+
+import (
+	"errors"
+	"sort"
+)
+
+// Returned by Restore for a snapshot that was never taken or already discarded
+var ErrUnknownSnapshot = errors.New("unknown snapshot")
+
+func copyPresets(presets map[string]bool) map[string]bool {
+	c := make(map[string]bool)
+	for p := range presets {
+		c[p] = true
+	}
+	return c
+}
+
+// Orders tables so that every table comes after the tables it references.
+// The references are given as table => referenced tables, self references and
+// cycles are ignored.
+func sortTables(tables []string, refs map[string][]string) []string {
+	sorted := append([]string{}, tables...)
+	sort.Strings(sorted)
+	known := make(map[string]bool)
+	for _, t := range sorted {
+		known[t] = true
+	}
+	var order []string
+	state := make(map[string]int) // 1: visiting, 2: done
+	var visit func(string)
+	visit = func(t string) {
+		if state[t] != 0 {
+			return
+		}
+		state[t] = 1
+		parents := append([]string{}, refs[t]...)
+		sort.Strings(parents)
+		for _, p := range parents {
+			if p != t && known[p] {
+				visit(p)
+			}
+		}
+		state[t] = 2
+		order = append(order, t)
+	}
+	for _, t := range sorted {
+		visit(t)
+	}
+	return order
+}
@@ -0,0 +1,22 @@
+package testchado
+
+import (
+    "reflect"
+    "testing"
+)
+
+func TestSortTables(t *testing.T) {
+    refs := map[string][]string{
+        "feature":              {"organism", "cvterm", "dbxref"},
+        "cvterm":               {"cv", "dbxref"},
+        "dbxref":               {"db"},
+        "feature_relationship": {"feature", "cvterm"},
+        "cvterm_relationship":  {"cvterm", "cvterm_relationship"},
+    }
+    tables := []string{"feature_relationship", "feature", "cvterm", "cv", "db", "dbxref", "organism", "cvterm_relationship"}
+    order := sortTables(tables, refs)
+    expected := []string{"cv", "db", "dbxref", "cvterm", "cvterm_relationship", "organism", "feature", "feature_relationship"}
+    if !reflect.DeepEqual(order, expected) {
+        t.Errorf("should have ordered tables by references, got %v", order)
+    }
+}
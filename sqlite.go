@@ -1,31 +1,128 @@
 package testchado
 
 import (
+	"context"
+	"database/sql"
+	"fmt"
 	"log"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
 
 	"github.com/jinzhu/gorm"
 	"github.com/jmoiron/sqlx"
-	_ "github.com/mattn/go-sqlite3"
+	"github.com/mattn/go-sqlite3"
 )
 
 // A type specific for sqlite backend
 type Sqlite struct {
 	*DBHelper
+	snapshots map[string]*sqliteSnapshot
+}
+
+// A copy of the database kept in a separate in memory database
+type sqliteSnapshot struct {
+	db      *sql.DB
+	presets map[string]bool
 }
 
 // Get a in memory instance of sqlite DBManager
 func NewSQLiteManager() *Sqlite {
-	gm, err := gorm.Open("sqlite3", ":memory:")
+	sqlite, err := NewSQLiteManagerE()
 	if err != nil {
 		log.Fatal(err)
 	}
+	return sqlite
+}
+
+// Get a in memory instance of sqlite DBManager, returns an error
+// instead of exiting if the database could not be opened
+func NewSQLiteManagerE() (*Sqlite, error) {
+	return newSqlite(":memory:")
+}
+
+// Get a file backed instance of sqlite DBManager. The file is created if it does
+// not exist and is kept afterwards, so that the chado database could be inspected
+// with the sqlite3 command line client.
+func NewSQLiteFileManager(file string) (*Sqlite, error) {
+	path, err := filepath.Abs(file)
+	if err != nil {
+		return nil, err
+	}
+	return newSqlite(path)
+}
+
+// Get a sqlite DBManager backed by a temporary file that is removed once the test
+// and all its subtests complete. If the test fails, the file is retained and its
+// path is logged for inspection.
+func NewSQLiteTempManager(t testing.TB) *Sqlite {
+	t.Helper()
+	dir, err := os.MkdirTemp("", "testchado")
+	if err != nil {
+		t.Fatalf("unable to create temporary directory: %s", err)
+	}
+	seed, err := testSeed(t.Name())
+	if err != nil {
+		t.Fatalf("unable to seed sqlite database manager: %s", err)
+	}
+	name := testSchemaName(t.Name(), rand.New(rand.NewSource(seed)))
+	file := filepath.Join(dir, strings.TrimPrefix(name, "tc_")+".sqlite3")
+	sqlite, err := NewSQLiteFileManager(file)
+	if err != nil {
+		os.RemoveAll(dir)
+		t.Fatalf("unable to create sqlite database: %s", err)
+	}
+	sqlite.reseed(seed)
+	t.Cleanup(func() {
+		sqlite.DBHandle().Close()
+		if t.Failed() {
+			t.Logf("chado database of the failed test is retained at %s", file)
+			return
+		}
+		os.RemoveAll(dir)
+	})
+	return sqlite
+}
+
+// Number of in memory databases opened so far
+var sqliteMemoryCount int64
+
+func newSqlite(source string) (*Sqlite, error) {
+	dsn := source
+	if source == ":memory:" {
+		// every connection of the pool, for example the one held by an
+		// isolated manager, has to see the same in memory database
+		dsn = fmt.Sprintf("file:testchado%d?mode=memory&cache=shared", atomic.AddInt64(&sqliteMemoryCount, 1))
+	}
+	gm, err := gorm.Open("sqlite3", dsn)
+	if err != nil {
+		return nil, err
+	}
+	if err := gm.DB().Ping(); err != nil {
+		gm.DB().Close()
+		return nil, err
+	}
 	gm.SingularTable(true)
 	sqlx := sqlx.NewDb(gm.DB(), "sqlite3")
-	return &Sqlite{&DBHelper{dbsource: ":memory:", driver: "sqlite3", dbhandler: sqlx, gormHandler: &gm}}
+	seed, err := newSeed()
+	if err != nil {
+		gm.DB().Close()
+		return nil, err
+	}
+	dbh := &DBHelper{dbsource: source, driver: "sqlite3", dbhandler: sqlx, gormHandler: &gm}
+	dbh.reseed(seed)
+	return &Sqlite{DBHelper: dbh}, nil
 }
 
+// Name of the database file, empty for an in memory database
 func (sqlite *Sqlite) Database() string {
-	return ""
+	if sqlite.dbsource == ":memory:" {
+		return ""
+	}
+	return filepath.Base(sqlite.dbsource)
 }
 
 func (sqlite *Sqlite) DropSchema() error {
@@ -36,27 +133,46 @@ func (sqlite *Sqlite) DropSchema() error {
 	if err != nil {
 		return err
 	}
-	tx := dbh.MustBegin()
+	tx, err := dbh.Begin()
+	if err != nil {
+		return err
+	}
 	for _, tbl := range tbls {
 		stmt := "DROP TABLE " + tbl.Name
-		_ = tx.MustExec(stmt)
+		if _, err := tx.Exec(stmt); err != nil {
+			tx.Rollback()
+			return &StatementError{Source: "DropSchema", Statement: stmt, Err: err}
+		}
 	}
 	err = tx.Commit()
 	if err != nil {
 		return err
 	}
 	sqlite.DBHelper.hasLoadedSchema = false
+	sqlite.DBHelper.loadedPresets = nil
+	sqlite.discardSnapshots()
 	return nil
 }
 
 func (sqlite *Sqlite) DeploySchema() error {
-	dbh := sqlite.DBHandle()
 	content, err := sqlite.SchemaDDL()
 	if err != nil {
 		return err
 	}
-	_ = dbh.MustExec(content.String())
+	// The schema manages its own transaction, so all statements has to
+	// run on the same connection
+	ctx := context.Background()
+	conn, err := sqlite.DBHandle().Conn(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	if err := execStatements(conn, "chado."+sqlite.Driver(), content.String()); err != nil {
+		conn.ExecContext(ctx, "ROLLBACK")
+		return err
+	}
 	sqlite.DBHelper.hasLoadedSchema = true
+	sqlite.DBHelper.loadedPresets = nil
 	return nil
 }
 
@@ -72,3 +188,88 @@ func (sqlite *Sqlite) ResetSchema() error {
 	sqlite.DBHelper.hasLoadedSchema = true
 	return nil
 }
+
+// Copies the current database into a separate in memory database using the
+// sqlite online backup API. An existing snapshot with the same name is replaced.
+func (sqlite *Sqlite) Snapshot(name string) error {
+	if !sqlite.hasLoadedSchema {
+		return ErrSchemaNotLoaded
+	}
+	db, err := sql.Open("sqlite3", ":memory:")
+	if err != nil {
+		return err
+	}
+	// every connection gets its own in memory database, so the copy
+	// has to stay in a single one
+	db.SetMaxOpenConns(1)
+	db.SetMaxIdleConns(1)
+	if err := sqliteBackup(db, sqlite.DBHandle().DB); err != nil {
+		db.Close()
+		return fmt.Errorf("unable to take snapshot %s: %s", name, err)
+	}
+	if sqlite.snapshots == nil {
+		sqlite.snapshots = make(map[string]*sqliteSnapshot)
+	}
+	if old, ok := sqlite.snapshots[name]; ok {
+		old.db.Close()
+	}
+	sqlite.snapshots[name] = &sqliteSnapshot{db: db, presets: copyPresets(sqlite.loadedPresets)}
+	return nil
+}
+
+// Copies a snapshot back into the current database
+func (sqlite *Sqlite) Restore(name string) error {
+	snap, ok := sqlite.snapshots[name]
+	if !ok {
+		return ErrUnknownSnapshot
+	}
+	if err := sqliteBackup(sqlite.DBHandle().DB, snap.db); err != nil {
+		return fmt.Errorf("unable to restore snapshot %s: %s", name, err)
+	}
+	sqlite.DBHelper.hasLoadedSchema = true
+	sqlite.DBHelper.loadedPresets = copyPresets(snap.presets)
+	return nil
+}
+
+func (sqlite *Sqlite) discardSnapshots() {
+	for _, snap := range sqlite.snapshots {
+		snap.db.Close()
+	}
+	sqlite.snapshots = nil
+}
+
+// Copies the main database of src into dst
+func sqliteBackup(dst, src *sql.DB) error {
+	ctx := context.Background()
+	dconn, err := dst.Conn(ctx)
+	if err != nil {
+		return err
+	}
+	defer dconn.Close()
+	sconn, err := src.Conn(ctx)
+	if err != nil {
+		return err
+	}
+	defer sconn.Close()
+	return dconn.Raw(func(dc interface{}) error {
+		return sconn.Raw(func(sc interface{}) error {
+			d, ok := dc.(*sqlite3.SQLiteConn)
+			if !ok {
+				return fmt.Errorf("expected sqlite3 connection, got %T", dc)
+			}
+			s, ok := sc.(*sqlite3.SQLiteConn)
+			if !ok {
+				return fmt.Errorf("expected sqlite3 connection, got %T", sc)
+			}
+			b, err := d.Backup("main", s, "main")
+			if err != nil {
+				return err
+			}
+			if _, err := b.Step(-1); err != nil {
+				b.Close()
+				return err
+			}
+			return b.Finish()
+		})
+	})
+}
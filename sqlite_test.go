@@ -2,6 +2,9 @@ package testchado
 
 import (
     "bytes"
+    "errors"
+    "os"
+    "path/filepath"
     "testing"
 )
 
@@ -148,3 +151,129 @@ func TestSQLiteLoadPresetFixture(t *testing.T) {
     }
 
 }
+
+func TestSQLiteLoadCustomFixture(t *testing.T) {
+    dbm, err := NewSQLiteManagerE()
+    if err != nil {
+        t.Fatalf("should have created sqlite manager: %s", err)
+    }
+    fixture := filepath.Join(t.TempDir(), "organism.sql")
+    content := `INSERT INTO organism (genus, species) VALUES ('Dictyostelium', 'discoideum');
+INSERT INTO organism (genus, species) VALUES ('Dictyostelium', 'purpureum');
+INSERT INTO organisms (genus, species) VALUES ('Homo', 'sapiens');
+`
+    if err := os.WriteFile(fixture, []byte(content), 0644); err != nil {
+        t.Fatal(err)
+    }
+    if err := dbm.LoadCustomFixture(fixture); !errors.Is(err, ErrSchemaNotLoaded) {
+        t.Errorf("should have not loaded custom fixture: %s", err)
+    }
+    _ = dbm.DeploySchema()
+    err = dbm.LoadCustomFixture(fixture)
+    var serr *StatementError
+    if !errors.As(err, &serr) {
+        t.Fatalf("should have returned a statement error: %s", err)
+    }
+    if serr.Source != fixture || serr.Line != 3 {
+        t.Errorf("should have reported line 3 of %s, got %s:%d", fixture, serr.Source, serr.Line)
+    }
+
+    type entries struct{ Counter int }
+    e := entries{}
+    err = dbm.DBHandle().Get(&e, "SELECT count(*) counter FROM organism")
+    if err != nil {
+        t.Errorf("should have executed the query %s", err)
+    }
+    if e.Counter != 0 {
+        t.Error("should have rolled back the fixture")
+    }
+}
+
+func TestSQLiteSnapshot(t *testing.T) {
+    dbm := NewSQLiteManager()
+    if err := dbm.Snapshot("fixture"); err != ErrSchemaNotLoaded {
+        t.Errorf("should not take snapshot without schema: %s", err)
+    }
+    _ = dbm.DeploySchema()
+    _ = dbm.LoadDefaultFixture()
+    if err := dbm.Snapshot("fixture"); err != nil {
+        t.Fatalf("should have taken snapshot: %s", err)
+    }
+
+    type entries struct{ Counter int }
+    e := entries{}
+    sqlx := dbm.DBHandle()
+    sqlx.MustExec("DELETE FROM organism")
+    if err := dbm.LoadPresetFixture("cvprop"); err == nil {
+        t.Error("should not have loaded cvprop on top of default fixture")
+    }
+    if err := dbm.Restore("fixture"); err != nil {
+        t.Fatalf("should have restored snapshot: %s", err)
+    }
+    if err := sqlx.Get(&e, "SELECT count(*) counter FROM organism"); err != nil {
+        t.Errorf("should have executed the query %s", err)
+    }
+    if e.Counter != 12 {
+        t.Errorf("should have restored 12 organisms, got %d", e.Counter)
+    }
+    if !dbm.loadedPresets["default"] {
+        t.Error("should have restored the loaded presets")
+    }
+    if err := dbm.Restore("pristine"); err != ErrUnknownSnapshot {
+        t.Errorf("should not restore unknown snapshot: %s", err)
+    }
+    _ = dbm.DropSchema()
+    if err := dbm.Restore("fixture"); err != ErrUnknownSnapshot {
+        t.Errorf("should have discarded snapshots with the schema: %s", err)
+    }
+}
+
+func TestSQLiteFileManager(t *testing.T) {
+    file := filepath.Join(t.TempDir(), "chado.sqlite3")
+    dbm, err := NewSQLiteFileManager(file)
+    if err != nil {
+        t.Fatalf("should have created file backed sqlite manager: %s", err)
+    }
+    if dbm.DataSource() != file {
+        t.Errorf("should have %s datasource, got %s", file, dbm.DataSource())
+    }
+    if dbm.Database() != "chado.sqlite3" {
+        t.Errorf("should have chado.sqlite3 database name, got %s", dbm.Database())
+    }
+    if err := dbm.DeploySchema(); err != nil {
+        t.Fatalf("error %s: should have deployed the chado schema", err)
+    }
+    _ = dbm.LoadDefaultFixture()
+    dbm.DBHandle().Close()
+
+    // reopen the file to check the content was persisted
+    dbm, err = NewSQLiteFileManager(file)
+    if err != nil {
+        t.Fatalf("should have reopened sqlite database: %s", err)
+    }
+    type entries struct{ Counter int }
+    e := entries{}
+    if err := dbm.DBHandle().Get(&e, "SELECT count(*) counter FROM organism"); err != nil {
+        t.Errorf("should have executed the query %s", err)
+    }
+    if e.Counter != 12 {
+        t.Errorf("should have persisted 12 organisms, got %d", e.Counter)
+    }
+}
+
+func TestSQLiteTempManager(t *testing.T) {
+    var file string
+    t.Run("temp", func(t *testing.T) {
+        dbm := NewSQLiteTempManager(t)
+        file = dbm.DataSource()
+        if _, err := os.Stat(file); err != nil {
+            t.Errorf("should have created the database file: %s", err)
+        }
+        if err := dbm.DeploySchema(); err != nil {
+            t.Errorf("error %s: should have deployed the chado schema", err)
+        }
+    })
+    if _, err := os.Stat(file); !os.IsNotExist(err) {
+        t.Errorf("should have removed the database file of a passing test: %s", err)
+    }
+}
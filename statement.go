@@ -0,0 +1,155 @@
+package testchado
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// Returned when a fixture is loaded before deploying the chado schema
+var ErrSchemaNotLoaded = errors.New("chado schema is not loaded")
+
+// StatementError reports a sql statement that failed while deploying the chado
+// schema or loading a fixture, along with its position in the source.
+type StatementError struct {
+	// Name of the schema or fixture file the statement came from
+	Source string
+	// Line in the source where the statement begins
+	Line int
+	// The failing statement
+	Statement string
+	// Error returned by the database
+	Err error
+}
+
+func (e *StatementError) Error() string {
+	stmt := e.Statement
+	if len(stmt) > 200 {
+		stmt = stmt[:200] + "..."
+	}
+	return fmt.Sprintf("%s:%d: %s\n\t%s", e.Source, e.Line, e.Err, stmt)
+}
+
+func (e *StatementError) Unwrap() error {
+	return e.Err
+}
+
+// A single sql statement along with the line where it begins
+type statement struct {
+	line int
+	sql  string
+}
+
+type execer interface {
+	ExecContext(context.Context, string, ...interface{}) (sql.Result, error)
+}
+
+// Executes all statements of content in order. The first failure stops the
+// execution and is returned as a *StatementError.
+func execStatements(ex execer, source, content string) error {
+	for _, stmt := range splitStatements(content) {
+		if _, err := ex.ExecContext(context.Background(), stmt.sql); err != nil {
+			return &StatementError{Source: source, Line: stmt.line, Statement: stmt.sql, Err: err}
+		}
+	}
+	return nil
+}
+
+// Splits sql content into individual statements terminated by semicolon.
+// Comments, quoted strings and identifiers and postgresql dollar quoted
+// strings are skipped while looking for the terminator.
+func splitStatements(content string) []statement {
+	var stmts []statement
+	start, startLine, line := -1, 0, 1
+	mark := func(i int) {
+		if start == -1 {
+			start, startLine = i, line
+		}
+	}
+	// moves past the closing delimiter, keeping track of line numbers
+	skipTo := func(i int, delim string) int {
+		end := strings.Index(content[i:], delim)
+		if end == -1 {
+			end = len(content) - i - len(delim)
+		}
+		line += strings.Count(content[i:i+end+len(delim)], "\n")
+		return i + end + len(delim)
+	}
+	for i := 0; i < len(content); {
+		c := content[i]
+		switch {
+		case c == '\n':
+			line++
+			i++
+		case strings.HasPrefix(content[i:], "--"):
+			end := strings.IndexByte(content[i:], '\n')
+			if end == -1 {
+				end = len(content) - i
+			}
+			i += end
+		case strings.HasPrefix(content[i:], "/*"):
+			i = skipTo(i+2, "*/")
+		case c == '\'' || c == '"':
+			mark(i)
+			i++
+			for i < len(content) {
+				if content[i] == '\n' {
+					line++
+				}
+				if content[i] == c {
+					// a doubled quote is an escaped one
+					if i+1 < len(content) && content[i+1] == c {
+						i += 2
+						continue
+					}
+					break
+				}
+				i++
+			}
+			i++
+		case c == '$':
+			mark(i)
+			if tag := dollarTag(content[i:]); tag != "" {
+				i = skipTo(i+len(tag), tag)
+			} else {
+				i++
+			}
+		case c == ';':
+			if start != -1 {
+				stmts = append(stmts, statement{line: startLine, sql: strings.TrimSpace(content[start:i])})
+				start = -1
+			}
+			i++
+		case c == ' ' || c == '\t' || c == '\r':
+			i++
+		default:
+			mark(i)
+			i++
+		}
+	}
+	if start != -1 && start < len(content) {
+		if rest := strings.TrimSpace(content[start:]); len(rest) > 0 {
+			stmts = append(stmts, statement{line: startLine, sql: rest})
+		}
+	}
+	return stmts
+}
+
+// Returns the opening tag of a dollar quoted string($$ or $tag$) at the
+// beginning of s, otherwise an empty string
+func dollarTag(s string) string {
+	for i := 1; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case c == '$':
+			return s[:i+1]
+		case c == '_' || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z'):
+		case c >= '0' && c <= '9' && i > 1:
+		default:
+			return ""
+		}
+	}
+	return ""
+}
@@ -0,0 +1,33 @@
+package testchado
+
+import (
+    "testing"
+)
+
+func TestSplitStatements(t *testing.T) {
+    content := `
+-- organism fixture; with a comment
+INSERT INTO organism (genus, species) VALUES ('Dictyostelium', 'discoideum');
+/* block comment;
+   spanning lines */
+INSERT INTO organism (genus, species, comment)
+    VALUES ('Homo', 'sapiens', 'it''s; quoted');
+CREATE FUNCTION noop() RETURNS void AS $body$ BEGIN; END; $body$ LANGUAGE plpgsql;
+SELECT $1, "semi;colon" FROM organism`
+    stmts := splitStatements(content)
+    if len(stmts) != 4 {
+        t.Fatalf("should have 4 statements, got %d", len(stmts))
+    }
+    lines := []int{3, 6, 8, 9}
+    for i, stmt := range stmts {
+        if stmt.line != lines[i] {
+            t.Errorf("statement %d should start at line %d, got %d", i, lines[i], stmt.line)
+        }
+    }
+    if stmts[1].sql != "INSERT INTO organism (genus, species, comment)\n    VALUES ('Homo', 'sapiens', 'it''s; quoted')" {
+        t.Errorf("should have kept the quoted semicolon: %s", stmts[1].sql)
+    }
+    if stmts[2].sql != "CREATE FUNCTION noop() RETURNS void AS $body$ BEGIN; END; $body$ LANGUAGE plpgsql" {
+        t.Errorf("should have kept the dollar quoted body: %s", stmts[2].sql)
+    }
+}
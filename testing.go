@@ -0,0 +1,74 @@
+package testchado
+
+import (
+	"math/rand"
+	"strings"
+	"testing"
+)
+
+// NewTestChado returns a Chado manager with a deployed chado schema that is dropped,
+// along with its database handles, once the test and all its subtests complete.
+// Like NewDBManager, it gives a postgres backend if TC_DSOURCE env variable is
+// set, otherwise a sqlite one.
+// The postgres schema is named after the test, so any leftover schema could be
+// traced back to the test that created it. The random source of the manager is
+// seeded from the test name and TC_SEED, the seed of a failing test is logged.
+// Setup failures, including the failing sql statement, stop the test.
+func NewTestChado(t testing.TB) Chado {
+	t.Helper()
+	dbm, err := NewDBManagerE()
+	if err != nil {
+		t.Fatalf("unable to create chado database manager: %s", err)
+	}
+	seed, err := testSeed(t.Name())
+	if err != nil {
+		t.Fatalf("unable to seed chado database manager: %s", err)
+	}
+	h, ok := dbm.(interface{ helper() *DBHelper })
+	if !ok {
+		t.Fatalf("unable to seed chado database manager %T", dbm)
+	}
+	dbh := h.helper()
+	dbh.reseed(seed)
+	if postgres, ok := dbm.(*Postgres); ok {
+		postgres.Schema = testSchemaName(t.Name(), dbh.rnd)
+	}
+	if err := dbm.DeploySchema(); err != nil {
+		t.Fatalf("unable to deploy chado schema: %s", err)
+	}
+	t.Cleanup(func() {
+		if t.Failed() {
+			base, _ := baseSeed()
+			t.Logf("reproduce the failed test with %s=%d", SeedEnv, base)
+		}
+		if err := dbm.DropSchema(); err != nil {
+			t.Errorf("unable to drop chado schema: %s", err)
+		}
+		dbm.DBHandle().Close()
+		dbm.GormHandle().DB().Close()
+	})
+	return dbm
+}
+
+// Maximum length of a postgresql identifier
+const maxIdentifierLen = 63
+
+// Returns a valid postgresql schema name made of the test name and a
+// random suffix that keeps it unique across runs
+func testSchemaName(name string, rnd *rand.Rand) string {
+	var b strings.Builder
+	for _, r := range strings.ToLower(name) {
+		switch {
+		case (r >= 'a' && r <= 'z') || (r >= '0' && r <= '9'):
+			b.WriteRune(r)
+		default:
+			b.WriteRune('_')
+		}
+	}
+	suffix := "_" + randomString(rnd, 6, 7)
+	prefix := "tc_" + strings.Trim(b.String(), "_")
+	if len(prefix)+len(suffix) > maxIdentifierLen {
+		prefix = prefix[:maxIdentifierLen-len(suffix)]
+	}
+	return prefix + suffix
+}
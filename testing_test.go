@@ -0,0 +1,55 @@
+package testchado
+
+import (
+    "math/rand"
+    "regexp"
+    "testing"
+)
+
+func TestNewTestChado(t *testing.T) {
+    var dbm DBManager
+    t.Run("setup", func(t *testing.T) {
+        dbm = NewTestChado(t)
+        if err := dbm.LoadDefaultFixture(); err != nil {
+            t.Errorf("should have loaded fixture in deployed schema: %s", err)
+        }
+    })
+    if err := dbm.LoadDefaultFixture(); err != ErrSchemaNotLoaded {
+        t.Errorf("should have dropped the schema after the test: %s", err)
+    }
+    if err := dbm.DBHandle().Ping(); err == nil {
+        t.Error("should have closed the database handle after the test")
+    }
+}
+
+func TestTestSchemaName(t *testing.T) {
+    name := testSchemaName("TestFeature/with-GFF3 loader", rand.New(rand.NewSource(7)))
+    if !regexp.MustCompile(`^tc_testfeature_with_gff3_loader_[a-z]{6}$`).MatchString(name) {
+        t.Errorf("should have named the schema after the test, got %s", name)
+    }
+    long := testSchemaName("TestAVeryLongTestNameThatWouldNeverFitInAPostgresqlIdentifierOfSixtyThreeBytes", rand.New(rand.NewSource(7)))
+    if len(long) != maxIdentifierLen {
+        t.Errorf("should have truncated the schema name to %d bytes, got %d", maxIdentifierLen, len(long))
+    }
+}
+
+func TestTestSeed(t *testing.T) {
+    seed, err := testSeed(t.Name())
+    if err != nil {
+        t.Fatal(err)
+    }
+    again, _ := testSeed(t.Name())
+    other, _ := testSeed(t.Name() + "/other")
+    if seed != again || seed == other {
+        t.Errorf("should have derived the seed from the test name, got %d %d %d", seed, again, other)
+    }
+    dbm := NewTestChado(t)
+    if dbm.Seed() != seed {
+        t.Errorf("should have seeded the manager with %d, got %d", seed, dbm.Seed())
+    }
+    first := testSchemaName(t.Name(), rand.New(rand.NewSource(seed)))
+    second := testSchemaName(t.Name(), rand.New(rand.NewSource(seed)))
+    if first != second {
+        t.Errorf("should have derived the schema name %s from the seed, got %s", first, second)
+    }
+}